@@ -0,0 +1,245 @@
+package eventlistener
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	bridgePollTimeout = 30 * time.Second
+	bridgeKeepAlive   = 15 * time.Second
+)
+
+// Bridge exposes the event stream of a listener over HTTP for clients which can't speak
+// the action monitor protocol or hold the token:
+//
+//	GET /events  Server-Sent Events, resumed from the Last-Event-ID header
+//	GET /poll    long polling, returns {"events": [...], "cursor": "..."} once events are available
+//
+// Both accept the query parameters types and casinos (comma separated IDs) to filter events,
+// and offsets with a cursor to resume from, e.g. "1:42,2:17" (the last received offset of each type).
+// The bridge streams whatever the listener is subscribed to. Recent events are kept in memory
+// to resume clients, older ones are read from history with ReadRange. A cursor of an event type
+// the listener isn't subscribed to is forbidden. History is returned in pages of at most
+// Broadcaster.HistoryLimit events: a poll returns right away with the cursor of the next page,
+// an event stream ends with it as the last event ID, so the client reconnects from there.
+type Bridge struct {
+	PollTimeout time.Duration // Maximum time a poll request waits for events.
+	KeepAlive   time.Duration // Period of SSE comments keeping idle connections open.
+
+	broadcaster *Broadcaster
+	mux         *http.ServeMux
+}
+
+// NewBridge creates a bridge keeping up to size recent events and attaches it to the listener.
+func NewBridge(listener *EventListener, size int) *Bridge {
+	b := &Bridge{
+		PollTimeout: bridgePollTimeout,
+		KeepAlive:   bridgeKeepAlive,
+		broadcaster: NewBroadcaster(listener, size),
+		mux:         http.NewServeMux(),
+	}
+	b.mux.HandleFunc("/events", b.serveEvents)
+	b.mux.HandleFunc("/poll", b.servePoll)
+
+	return b
+}
+
+func (b *Bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mux.ServeHTTP(w, r)
+}
+
+type pollResponse struct {
+	Events []*Event `json:"events"`
+	Cursor string   `json:"cursor"`
+}
+
+func (b *Bridge) servePoll(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	timeout := b.PollTimeout
+	if value := r.URL.Query().Get("timeout"); value != "" {
+		if timeout, err = time.ParseDuration(value); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if timeout > b.PollTimeout {
+			timeout = b.PollTimeout
+		}
+	}
+
+	cursor, err := ParseCursor(r.URL.Query().Get("offsets"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	stream, events, err := b.broadcaster.Open(ctx, cursor, filter)
+	if err != nil {
+		http.Error(w, err.Error(), openStatus(err))
+		return
+	}
+
+	events = append(events, stream.Buffered()...)
+	if len(events) == 0 && !stream.Truncated() {
+		events, err = stream.Next(ctx)
+		if err != nil && err != context.DeadlineExceeded {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	if events == nil {
+		events = make([]*Event, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(pollResponse{Events: events, Cursor: stream.Cursor().String()})
+}
+
+func (b *Bridge) serveEvents(w http.ResponseWriter, r *http.Request) {
+	log := b.broadcaster.listener.logger().Named("bridge")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	offsets := r.Header.Get("Last-Event-ID")
+	if offsets == "" {
+		offsets = r.URL.Query().Get("offsets")
+	}
+
+	position, err := ParseCursor(offsets)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	stream, events, err := b.broadcaster.Open(ctx, position, filter)
+	if err != nil {
+		http.Error(w, err.Error(), openStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	if stream.Truncated() {
+		events = append(events, stream.Buffered()...)
+		if err := writeServerSentEvents(w, position, events); err != nil {
+			log.Debug("write", "error", err)
+			return
+		}
+
+		// Without data the client only takes the ID, and reconnects from it
+		if _, err := fmt.Fprintf(w, "id: %s\n\n", stream.Cursor()); err != nil {
+			log.Debug("write", "error", err)
+		}
+		flusher.Flush()
+		return
+	}
+
+	for {
+		if err := writeServerSentEvents(w, position, events); err != nil {
+			log.Debug("write", "error", err)
+			return
+		}
+		flusher.Flush()
+
+		waitContext, cancel := context.WithTimeout(ctx, b.KeepAlive)
+		events, err = stream.Next(waitContext)
+		cancel()
+
+		switch {
+		case err == context.DeadlineExceeded && ctx.Err() == nil:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case err != nil:
+			return
+		}
+	}
+}
+
+// openStatus returns the HTTP status of a stream which failed to open.
+func openStatus(err error) int {
+	if errors.Is(err, ErrNotSubscribed) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadGateway
+}
+
+// writeServerSentEvents writes the events, the ID of each one is the client position after it.
+func writeServerSentEvents(w http.ResponseWriter, position Cursor, events []*Event) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		position[event.EventType] = event.Offset
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", position, event.EventType.ToString(), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseEventFilter(r *http.Request) (*EventFilter, error) {
+	query := r.URL.Query()
+	filter := &EventFilter{
+		EventTypes: make(map[EventType]struct{}),
+		CasinoIDs:  make(map[uint64]struct{}),
+	}
+
+	for _, value := range splitList(query.Get("types")) {
+		eventType, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event type %q", value)
+		}
+		filter.EventTypes[EventType(eventType)] = struct{}{}
+	}
+
+	for _, value := range splitList(query.Get("casinos")) {
+		casinoID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid casino ID %q", value)
+		}
+		filter.CasinoIDs[casinoID] = struct{}{}
+	}
+
+	return filter, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
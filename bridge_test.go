@@ -0,0 +1,317 @@
+package eventlistener
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCursor(t *testing.T) {
+	cursor, err := ParseCursor("2:17,1:42")
+	require.NoError(t, err)
+	assert.Equal(t, Cursor{1: 42, 2: 17}, cursor)
+	assert.Equal(t, "1:42,2:17", cursor.String())
+
+	cursor, err = ParseCursor("")
+	require.NoError(t, err)
+	assert.Empty(t, cursor)
+
+	_, err = ParseCursor("1-42")
+	assert.Error(t, err)
+}
+
+func TestBroadcaster_resume(t *testing.T) {
+	b := newBroadcaster(2)
+	for i := uint64(0); i < 4; i++ {
+		require.NoError(t, b.Write(context.Background(), &EventMessage{Events: []*Event{{EventType: 1, Offset: i}}}))
+	}
+
+	stream, gaps, unbuffered := b.resume(Cursor{1: 0}, &EventFilter{})
+	assert.Equal(t, map[EventType][2]uint64{1: {1, 1}}, gaps)
+	assert.Empty(t, unbuffered)
+
+	events := stream.Buffered()
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(2), events[0].Offset)
+	assert.Equal(t, Cursor{1: 3}, stream.cursor)
+
+	stream, gaps, _ = b.resume(Cursor{1: 2}, &EventFilter{})
+	assert.Empty(t, gaps)
+	events = stream.Buffered()
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(3), events[0].Offset)
+
+	// Cursors older than the first buffered event, e.g. after a restart, have a gap up to it
+	b = newBroadcaster(10)
+	require.NoError(t, b.Write(context.Background(), &EventMessage{Events: []*Event{{EventType: 1, Offset: 5}, {EventType: 1, Offset: 6}}}))
+
+	_, gaps, unbuffered = b.resume(Cursor{1: 2, 2: 0, 3: 0}, &EventFilter{EventTypes: map[EventType]struct{}{1: {}, 2: {}}})
+	assert.Equal(t, map[EventType][2]uint64{1: {3, 4}}, gaps)
+	assert.Equal(t, []EventType{2}, unbuffered)
+
+	_, gaps, _ = b.resume(Cursor{1: 4}, &EventFilter{})
+	assert.Empty(t, gaps)
+}
+
+func TestBroadcaster_Open(t *testing.T) {
+	server := newTestServer(t)
+	for i := 0; i < 3; i++ {
+		server.publish(1, `{}`)
+		server.publish(2, `{}`)
+	}
+
+	listener := newTestListener(t, server, nil)
+	listener.RangeIdleWait = 100 * time.Millisecond
+	broadcaster := NewBroadcaster(listener, 10)
+
+	// The listener starts after the cursor, as after a restart of a bridge
+	ok, err := listener.Subscribe(1, 2)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = listener.Subscribe(2, 3)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		broadcaster.Lock()
+		defer broadcaster.Unlock()
+		return broadcaster.count == 1
+	}, waitEventsTimeout, 10*time.Millisecond)
+
+	stream, events, err := broadcaster.Open(context.Background(), Cursor{1: 0, 2: 0}, &EventFilter{})
+	require.NoError(t, err)
+
+	offsets := make(map[EventType][]uint64)
+	for _, event := range events {
+		offsets[event.EventType] = append(offsets[event.EventType], event.Offset)
+	}
+	assert.Equal(t, map[EventType][]uint64{1: {1}, 2: {1, 2}}, offsets)
+
+	buffered := stream.Buffered()
+	require.Len(t, buffered, 1)
+	assert.Equal(t, uint64(2), buffered[0].Offset)
+	assert.Equal(t, Cursor{1: 2, 2: 2}, stream.Cursor())
+}
+
+func TestBroadcaster_OpenNotSubscribed(t *testing.T) {
+	server := newTestServer(t)
+	server.publish(7, `{}`)
+
+	listener := newTestListener(t, server, nil)
+	broadcaster := NewBroadcaster(listener, 10)
+	ok, err := listener.Subscribe(1, offset)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, _, err = broadcaster.Open(context.Background(), Cursor{1: 0, 7: 0}, &EventFilter{})
+	assert.True(t, errors.Is(err, ErrNotSubscribed))
+
+	// Filtered out, the cursor of event_7 is ignored
+	filter := &EventFilter{EventTypes: map[EventType]struct{}{1: {}}}
+	_, events, err := broadcaster.Open(context.Background(), Cursor{1: 0, 7: 0}, filter)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestBroadcaster_OpenTruncated(t *testing.T) {
+	server := newTestServer(t)
+	for i := 0; i < 5; i++ {
+		server.publish(1, `{}`)
+		server.publish(2, `{}`)
+	}
+
+	listener := newTestListener(t, server, nil)
+	broadcaster := NewBroadcaster(listener, 10)
+	broadcaster.HistoryLimit = 3
+	_, err := listener.BatchSubscribe([]EventType{1, 2}, 5)
+	require.NoError(t, err)
+
+	// Pages of 3 events, the filtered out ones count and are skipped by the cursor
+	filter := &EventFilter{CasinoIDs: map[uint64]struct{}{0: {}}}
+	var offsets []string
+	cursor := Cursor{1: 0, 2: 1}
+	for pages := 0; ; pages++ {
+		require.True(t, pages < 5)
+
+		stream, events, err := broadcaster.Open(context.Background(), cursor, filter)
+		require.NoError(t, err)
+		for _, event := range events {
+			offsets = append(offsets, fmt.Sprintf("%d:%d", event.EventType, event.Offset))
+		}
+		cursor = stream.Cursor()
+
+		if !stream.Truncated() {
+			assert.Equal(t, 2, pages)
+			break
+		}
+		assert.Empty(t, stream.Buffered())
+	}
+
+	assert.Equal(t, []string{"1:1", "1:2", "1:3", "1:4", "2:2", "2:3", "2:4"}, offsets)
+	assert.Equal(t, Cursor{1: 4, 2: 4}, cursor)
+}
+
+func newTestBridge(t *testing.T, size int) (*testServer, *Bridge, *httptest.Server) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+	listener.RangeIdleWait = 100 * time.Millisecond
+
+	bridge := NewBridge(listener, size)
+	httpServer := httptest.NewServer(bridge)
+	t.Cleanup(httpServer.Close)
+
+	_, err := listener.BatchSubscribe([]EventType{1, 2}, offset)
+	require.NoError(t, err)
+	return server, bridge, httpServer
+}
+
+func poll(t *testing.T, url string) *pollResponse {
+	response, err := http.Get(url)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	result := new(pollResponse)
+	require.NoError(t, json.NewDecoder(response.Body).Decode(result))
+	return result
+}
+
+func TestBridge_poll(t *testing.T) {
+	server, _, httpServer := newTestBridge(t, 1)
+
+	server.publish(1, `{}`)
+	server.publish(2, `{}`)
+	server.publish(1, `{}`)
+	server.publish(1, `{}`)
+	time.Sleep(50 * time.Millisecond)
+
+	// Offset 1 of event_1 was evicted from the buffer and is read from history
+	result := poll(t, httpServer.URL+"/poll?types=1&offsets=1:0")
+	require.Len(t, result.Events, 2)
+	assert.Equal(t, uint64(1), result.Events[0].Offset)
+	assert.Equal(t, uint64(2), result.Events[1].Offset)
+	assert.Equal(t, "1:2", result.Cursor)
+
+	result = poll(t, httpServer.URL+"/poll?types=2&timeout=10ms")
+	assert.Empty(t, result.Events)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.publish(2, `{}`)
+	}()
+	result = poll(t, httpServer.URL+"/poll?types=2&offsets="+result.Cursor)
+	require.Len(t, result.Events, 1)
+	assert.Equal(t, EventType(2), result.Events[0].EventType)
+	assert.Equal(t, uint64(1), result.Events[0].Offset)
+
+	response, err := http.Get(httpServer.URL + "/poll?types=x")
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	response, err = http.Get(httpServer.URL + "/poll?offsets=7:0")
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+}
+
+func TestBridge_pollTruncated(t *testing.T) {
+	server, bridge, httpServer := newTestBridge(t, 1)
+	bridge.broadcaster.HistoryLimit = 2
+
+	for i := 0; i < 5; i++ {
+		server.publish(1, `{}`)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// Returns the first page without waiting, offset 4 is held back
+	result := poll(t, httpServer.URL+"/poll?offsets=1:0")
+	require.Len(t, result.Events, 2)
+	assert.Equal(t, uint64(2), result.Events[1].Offset)
+	assert.Equal(t, "1:2", result.Cursor)
+
+	result = poll(t, httpServer.URL+"/poll?offsets="+result.Cursor)
+	require.Len(t, result.Events, 2)
+	assert.Equal(t, uint64(3), result.Events[0].Offset)
+	assert.Equal(t, uint64(4), result.Events[1].Offset)
+	assert.Equal(t, "1:4", result.Cursor)
+}
+
+func TestBridge_eventsTruncated(t *testing.T) {
+	server, bridge, httpServer := newTestBridge(t, 1)
+	bridge.broadcaster.HistoryLimit = 2
+
+	for i := 0; i < 5; i++ {
+		server.publish(1, `{}`)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	request, err := http.NewRequest(http.MethodGet, httpServer.URL+"/events", nil)
+	require.NoError(t, err)
+	request.Header.Set("Last-Event-ID", "1:0")
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	// The stream ends after the page with the ID to reconnect from
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	var ids []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		}
+	}
+	assert.Equal(t, []string{"1:1", "1:2", "1:2"}, ids)
+}
+
+func TestBridge_events(t *testing.T) {
+	server, _, httpServer := newTestBridge(t, 10)
+
+	server.publish(1, `{}`)
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	request, err := http.NewRequest(http.MethodGet, httpServer.URL+"/events?types=1", nil)
+	require.NoError(t, err)
+	request.Header.Set("Last-Event-ID", "1:0,2:5")
+	request = request.WithContext(ctx)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	server.publish(2, `{}`)
+	server.publish(1, `{"a":1}`)
+
+	reader := bufio.NewReader(response.Body)
+	lines := make([]string, 0, 3)
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	assert.Equal(t, "id: 1:1,2:5", lines[0])
+	assert.Equal(t, "event: event_1", lines[1])
+
+	event := new(Event)
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), event))
+	assert.Equal(t, uint64(1), event.Offset)
+	assert.JSONEq(t, `{"a":1}`, string(event.Data))
+}
@@ -0,0 +1,394 @@
+package eventlistener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	broadcastBufferSize   = 10000
+	broadcastHistoryLimit = 1000
+)
+
+// ErrNotSubscribed is returned by Broadcaster.Open for a cursor of an event type the listener isn't subscribed to.
+var ErrNotSubscribed = errors.New("event type not subscribed")
+
+// Cursor holds the last delivered offset of each event type of a stream.
+type Cursor map[EventType]uint64
+
+// ParseCursor parses a cursor formatted by Cursor.String, e.g. "1:42,2:17".
+func ParseCursor(s string) (Cursor, error) {
+	cursor := make(Cursor)
+	if s == "" {
+		return cursor, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		pair := strings.SplitN(part, ":", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid cursor %q", s)
+		}
+
+		eventType, err := strconv.Atoi(pair[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor %q: %v", s, err)
+		}
+		offset, err := strconv.ParseUint(pair[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor %q: %v", s, err)
+		}
+
+		cursor[EventType(eventType)] = offset
+	}
+	return cursor, nil
+}
+
+func (c Cursor) String() string {
+	eventTypes := make([]int, 0, len(c))
+	for eventType := range c {
+		eventTypes = append(eventTypes, int(eventType))
+	}
+	sort.Ints(eventTypes)
+
+	parts := make([]string, len(eventTypes))
+	for i, eventType := range eventTypes {
+		parts[i] = fmt.Sprintf("%d:%d", eventType, c[EventType(eventType)])
+	}
+	return strings.Join(parts, ",")
+}
+
+// EventFilter selects events by type and casino, empty sets match everything.
+type EventFilter struct {
+	EventTypes map[EventType]struct{}
+	CasinoIDs  map[uint64]struct{}
+}
+
+func (f *EventFilter) Match(event *Event) bool {
+	if !f.matchType(event.EventType) {
+		return false
+	}
+	if len(f.CasinoIDs) > 0 {
+		if _, ok := f.CasinoIDs[event.CasinoID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *EventFilter) matchType(eventType EventType) bool {
+	if len(f.EventTypes) == 0 {
+		return true
+	}
+	_, ok := f.EventTypes[eventType]
+	return ok
+}
+
+type broadcastEntry struct {
+	seq   uint64
+	event *Event
+}
+
+// Broadcaster fans out the events of a listener to any number of streams, e.g. clients of a bridge.
+// It keeps recent events in a ring buffer to resume streams, older ones are read from history with ReadRange.
+type Broadcaster struct {
+	HistoryLimit int // Maximum number of events read from history by Open, 1000 if it isn't positive.
+
+	listener *EventListener
+
+	sync.Mutex
+	ring   []broadcastEntry
+	start  int
+	count  int
+	seq    uint64
+	notify chan struct{}
+	closed bool
+
+	first   map[EventType]uint64 // first buffered offset
+	last    map[EventType]uint64 // last buffered offset
+	evicted map[EventType]uint64 // last offset dropped from the buffer
+}
+
+// NewBroadcaster creates a broadcaster keeping up to size recent events and attaches it to the listener as a sink.
+func NewBroadcaster(listener *EventListener, size int) *Broadcaster {
+	if size <= 0 {
+		size = broadcastBufferSize
+	}
+
+	b := newBroadcaster(size)
+	b.HistoryLimit = broadcastHistoryLimit
+	b.listener = listener
+	listener.AddSink(b)
+	return b
+}
+
+func newBroadcaster(size int) *Broadcaster {
+	return &Broadcaster{
+		ring:    make([]broadcastEntry, size),
+		notify:  make(chan struct{}),
+		first:   make(map[EventType]uint64),
+		last:    make(map[EventType]uint64),
+		evicted: make(map[EventType]uint64),
+	}
+}
+
+func (b *Broadcaster) Write(_ context.Context, message *EventMessage) error {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, event := range message.Events {
+		b.seq++
+		entry := broadcastEntry{seq: b.seq, event: event}
+
+		if b.count == len(b.ring) {
+			oldest := b.ring[b.start].event
+			b.evicted[oldest.EventType] = oldest.Offset
+			b.ring[b.start] = entry
+			b.start = (b.start + 1) % len(b.ring)
+		} else {
+			b.ring[(b.start+b.count)%len(b.ring)] = entry
+			b.count++
+		}
+		if _, ok := b.first[event.EventType]; !ok {
+			b.first[event.EventType] = event.Offset
+		}
+		b.last[event.EventType] = event.Offset
+	}
+
+	close(b.notify)
+	b.notify = make(chan struct{})
+	return nil
+}
+
+func (b *Broadcaster) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.notify)
+	}
+	return nil
+}
+
+// Stream is the position of one client in the broadcaster.
+type Stream struct {
+	broadcaster *Broadcaster
+	seq         uint64
+	cursor      Cursor
+	filter      *EventFilter
+	behind      map[EventType]struct{} // event types with history left to read, held back
+}
+
+// Open starts a stream of the events matching filter after the cursor, or live if the cursor is empty.
+// The event types missing in the cursor start live. Returns the events since the cursor which
+// the buffer misses, evicted or older than its first event, read from history. They go before
+// the ones returned by Next. The history of event types not buffered yet, e.g. after a restart,
+// is read up to the head offset of the server.
+//
+// History is read only for the event types the listener is subscribed to, a cursor of another
+// event type matching the filter fails with ErrNotSubscribed. At most HistoryLimit events are read,
+// see Stream.Truncated.
+func (b *Broadcaster) Open(ctx context.Context, cursor Cursor, filter *EventFilter) (*Stream, []*Event, error) {
+	if len(cursor) == 0 {
+		return b.live(filter), nil, nil
+	}
+
+	for eventType := range cursor {
+		if filter.matchType(eventType) && !b.listener.subscribed(eventType) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrNotSubscribed, eventType.ToString())
+		}
+	}
+
+	stream, gaps, unbuffered := b.resume(cursor, filter)
+
+	if len(unbuffered) > 0 {
+		heads, err := b.listener.HeadOffsets(ctx, unbuffered...)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, eventType := range unbuffered {
+			if head := heads[eventType]; head > cursor[eventType]+1 {
+				gaps[eventType] = [2]uint64{cursor[eventType] + 1, head - 1}
+			}
+		}
+	}
+
+	eventTypes := make([]EventType, 0, len(gaps))
+	for eventType := range gaps {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Slice(eventTypes, func(i, j int) bool { return eventTypes[i] < eventTypes[j] })
+
+	limit := uint64(broadcastHistoryLimit)
+	if b.HistoryLimit > 0 {
+		limit = uint64(b.HistoryLimit)
+	}
+
+	var events []*Event
+	for _, eventType := range eventTypes {
+		if limit == 0 {
+			stream.behind[eventType] = struct{}{}
+			continue
+		}
+
+		gap := gaps[eventType]
+		truncated := gap[1]-gap[0] >= limit
+		if truncated {
+			gap[1] = gap[0] + limit - 1
+		}
+		limit -= gap[1] - gap[0] + 1
+
+		history, err := b.listener.ReadRange(ctx, eventType, gap[0], gap[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, event := range history {
+			if stream.accept(event) {
+				events = append(events, event)
+			}
+		}
+
+		// The next stream continues after the events read, including the filtered out ones
+		if truncated {
+			stream.cursor[eventType] = gap[1]
+			stream.behind[eventType] = struct{}{}
+		}
+	}
+
+	return stream, events, nil
+}
+
+func (b *Broadcaster) live(filter *EventFilter) *Stream {
+	b.Lock()
+	defer b.Unlock()
+
+	return &Stream{
+		broadcaster: b,
+		seq:         b.seq,
+		cursor:      make(Cursor),
+		filter:      filter,
+	}
+}
+
+// resume starts a stream after the cursor and returns the ranges of offsets missing in the buffer,
+// and the event types of the cursor without buffered events.
+func (b *Broadcaster) resume(cursor Cursor, filter *EventFilter) (*Stream, map[EventType][2]uint64, []EventType) {
+	b.Lock()
+	defer b.Unlock()
+
+	stream := &Stream{broadcaster: b, cursor: make(Cursor), filter: filter, behind: make(map[EventType]struct{})}
+	for eventType, offset := range cursor {
+		stream.cursor[eventType] = offset
+	}
+	for eventType, offset := range b.last {
+		if _, ok := stream.cursor[eventType]; !ok && filter.matchType(eventType) {
+			stream.cursor[eventType] = offset
+		}
+	}
+
+	gaps := make(map[EventType][2]uint64)
+	var unbuffered []EventType
+	for eventType, offset := range cursor {
+		if !filter.matchType(eventType) {
+			continue
+		}
+
+		first, ok := b.first[eventType]
+		if !ok {
+			unbuffered = append(unbuffered, eventType)
+			continue
+		}
+
+		// The gap ends before the oldest event left in the buffer
+		if evicted, ok := b.evicted[eventType]; ok && evicted > offset {
+			gaps[eventType] = [2]uint64{offset + 1, evicted}
+		} else if first > offset+1 {
+			gaps[eventType] = [2]uint64{offset + 1, first - 1}
+		}
+	}
+
+	return stream, gaps, unbuffered
+}
+
+// read returns the new events of the stream and advances it.
+// If there are none, returns a channel closed when new events arrive.
+func (b *Broadcaster) read(stream *Stream) ([]*Event, <-chan struct{}, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	var events []*Event
+	for i := 0; i < b.count; i++ {
+		entry := b.ring[(b.start+i)%len(b.ring)]
+		if entry.seq <= stream.seq {
+			continue
+		}
+		stream.seq = entry.seq
+		if stream.accept(entry.event) {
+			events = append(events, entry.event)
+		}
+	}
+	stream.seq = b.seq
+
+	return events, b.notify, b.closed
+}
+
+// accept reports whether the event passes the filter, isn't held back and is newer than the cursor, and moves the cursor.
+func (s *Stream) accept(event *Event) bool {
+	if !s.filter.Match(event) {
+		return false
+	}
+	if _, ok := s.behind[event.EventType]; ok {
+		return false
+	}
+	if offset, ok := s.cursor[event.EventType]; ok && event.Offset <= offset {
+		return false
+	}
+	s.cursor[event.EventType] = event.Offset
+	return true
+}
+
+// Truncated reports whether Open left history to read because of Broadcaster.HistoryLimit.
+// The stream holds back the events of those types, open another one from Cursor to continue.
+func (s *Stream) Truncated() bool {
+	return len(s.behind) > 0
+}
+
+// Buffered returns the new events without waiting.
+func (s *Stream) Buffered() []*Event {
+	events, _, _ := s.broadcaster.read(s)
+	return events
+}
+
+// Next blocks until the stream has new events.
+// Returns ListenerClosed once the listener is closed.
+func (s *Stream) Next(ctx context.Context) ([]*Event, error) {
+	for {
+		events, notify, closed := s.broadcaster.read(s)
+		if len(events) > 0 {
+			return events, nil
+		}
+		if closed {
+			return nil, ListenerClosed
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+// Cursor returns the last offset of each event type delivered by the stream.
+func (s *Stream) Cursor() Cursor {
+	cursor := make(Cursor, len(s.cursor))
+	for eventType, offset := range s.cursor {
+		cursor[eventType] = offset
+	}
+	return cursor
+}
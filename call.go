@@ -0,0 +1,34 @@
+package eventlistener
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Call sends a request of the method with the params and decodes the result into result, unless it is nil.
+// It reaches the methods of the action monitor without a method of their own here.
+// An error response of the server is returned as *RPCError, the context cancels waiting for the response.
+// In strict mode a response which isn't valid JSON-RPC 2.0 is returned as ErrInvalidResponse.
+func (e *EventListener) Call(ctx context.Context, method string, params, result interface{}) error {
+	return e.call(ctx, nil, newRequestMessage(method, params), result)
+}
+
+// call sends the request on the session, or on any connection if the session is nil.
+func (e *EventListener) call(ctx context.Context, s *session, request *requestMessage, result interface{}) error {
+	response, err := e.sendSessionRequest(ctx, s, request)
+	if err != nil {
+		return err
+	}
+
+	if response.err != nil {
+		return response.err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
@@ -0,0 +1,53 @@
+package eventlistener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEventListener_Call(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec} {
+		t.Run(fmt.Sprintf("%T", codec), func(t *testing.T) {
+			listener := New(server.addr(), WithCodec(codec))
+			require.NoError(t, listener.ListenAndServe(context.Background()))
+			defer listener.Close()
+
+			type head struct {
+				Topic  string `json:"topic"`
+				Offset uint64 `json:"offset"`
+			}
+			result := new(head)
+			require.NoError(t, listener.Call(context.Background(), "echo", head{"event_1", 18446744073709551615}, result))
+			assert.Equal(t, &head{"event_1", 18446744073709551615}, result)
+
+			require.NoError(t, listener.Call(context.Background(), "echo", nil, nil))
+
+			err := listener.Call(context.Background(), "serverInfo", nil, result)
+			rpcError := new(RPCError)
+			require.True(t, errors.As(err, &rpcError), err)
+			assert.Equal(t, &RPCError{Code: CodeMethodNotFound, Message: "method not found"}, rpcError)
+		})
+	}
+}
+
+func TestEventListener_Call_context(t *testing.T) {
+	server := newTestServer(t)
+	server.silent = true
+
+	listener := NewEventListener(server.addr(), nil)
+	require.NoError(t, listener.ListenAndServe(context.Background()))
+	defer listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := listener.Call(ctx, "echo", nil, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
@@ -0,0 +1,392 @@
+package eventlistener
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"sync/atomic"
+)
+
+// Codec encodes requests and decodes responses and events on the wire.
+// A codec other than JSON is negotiated as a websocket subprotocol, the listener falls back to JSON
+// if the server doesn't support it. Marshal and Unmarshal must use the json struct tags.
+type Codec interface {
+	// Subprotocol is the websocket subprotocol of the codec, empty for JSON.
+	Subprotocol() string
+	// FrameType is the websocket message type of the frames, websocket.TextMessage or websocket.BinaryMessage.
+	FrameType() int
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+	CBORCodec    Codec = cborCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Subprotocol() string {
+	return ""
+}
+
+func (jsonCodec) FrameType() int {
+	return websocket.TextMessage
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// decodeFrame decodes a frame into a response to a request or an event batch.
+// Frames without an id are events unless they have a method or an error: notifications and errors
+// pushed by the server are returned as responses without an id. So are frames whose result isn't
+// an event batch, as unknown notifications with the result as params.
+// Whatever the codec, response results, params and event data are returned as JSON.
+// Binary frames decoded by reflection are counted, counters may be nil.
+func decodeFrame(codec Codec, message []byte, counters *counters) (*responseMessage, *EventMessage, error) {
+	switch codec.(type) {
+	case jsonCodec:
+		return decodeJSONFrame(message)
+	case msgpackCodec, cborCodec:
+		return decodeBinaryFrame(codec, message, counters)
+	default:
+		return decodeGenericFrame(codec, message)
+	}
+}
+
+// decodeJSONFrame peeks for the id and decodes the result once, events straight into a pooled message.
+func decodeJSONFrame(message []byte) (*responseMessage, *EventMessage, error) {
+	members, err := scanFrame(message)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var jsonrpc string
+	if len(members.jsonrpc) > 0 {
+		if err := json.Unmarshal(members.jsonrpc, &jsonrpc); err != nil {
+			return nil, nil, err
+		}
+	}
+	rpcError, err := decodeRPCError(members.error)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isSet(members.id) {
+		response := &responseMessage{JSONRPC: jsonrpc, ID: new(string), Result: members.result, Error: rpcError}
+		if err := json.Unmarshal(members.id, response.ID); err != nil {
+			return nil, nil, err
+		}
+		return response, nil, nil
+	}
+
+	if len(members.method) > 0 || rpcError != nil {
+		notification := &responseMessage{JSONRPC: jsonrpc, Params: members.params, Error: rpcError}
+		if len(members.method) > 0 {
+			if err := json.Unmarshal(members.method, &notification.Method); err != nil {
+				return nil, nil, err
+			}
+		}
+		return notification, nil, nil
+	}
+
+	if !isSet(members.result) {
+		return &responseMessage{JSONRPC: jsonrpc, Params: members.result}, nil, nil
+	}
+
+	eventMessage := acquireEventMessage()
+	if err := json.Unmarshal(members.result, eventMessage); err != nil {
+		eventMessage.pooled = true
+		eventMessage.Release()
+		return &responseMessage{JSONRPC: jsonrpc, Params: members.result}, nil, nil
+	}
+	return nil, eventMessage, nil
+}
+
+// isSet reports whether a scanned member is present and not null.
+func isSet(member []byte) bool {
+	return len(member) > 0 && string(member) != "null"
+}
+
+// binaryRaw keeps a value of a binary codec undecoded.
+type binaryRaw []byte
+
+type binaryFrame struct {
+	JSONRPC string    `json:"jsonrpc"`
+	ID      *string   `json:"id"`
+	Result  binaryRaw `json:"result"`
+	Error   binaryRaw `json:"error"`
+	Method  string    `json:"method"`
+	Params  binaryRaw `json:"params"`
+}
+
+type binaryEvent struct {
+	Offset    uint64    `json:"offset"`
+	Sender    string    `json:"sender"`
+	CasinoID  uint64    `json:"casino_id"`
+	GameID    uint64    `json:"game_id"`
+	RequestID uint64    `json:"req_id"`
+	EventType EventType `json:"event_type"`
+	Data      binaryRaw `json:"data"`
+}
+
+type binaryEventMessage struct {
+	Offset uint64         `json:"offset"`
+	Events []*binaryEvent `json:"events"`
+}
+
+// decodeBinaryFrame scans the frame and decodes its values directly, events into a pooled message.
+// The codec libraries decode by reflection into intermediate values, which made binary frames
+// slower to decode than JSON. Only frames with values the scanner leaves to the codec, e.g. extensions,
+// are decoded by reflection and counted. Malformed frames fail once, with the error of the scanner.
+func decodeBinaryFrame(codec Codec, message []byte, counters *counters) (*responseMessage, *EventMessage, error) {
+	if scanner, ok := codec.(binaryScanner); ok {
+		response, eventMessage, err := decodeScannedFrame(scanner, message)
+		if err != errTranscode {
+			return response, eventMessage, err
+		}
+		if counters != nil {
+			atomic.AddUint64(&counters.decodeFallbacks, 1)
+		}
+	}
+	return decodeReflectedFrame(codec, message)
+}
+
+// decodeScannedFrame decodes a binary frame like decodeJSONFrame.
+func decodeScannedFrame(scanner binaryScanner, message []byte) (*responseMessage, *EventMessage, error) {
+	members, err := scanBinaryFrame(scanner, message)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var jsonrpc, method []byte
+	if binaryIsSet(scanner, members.jsonrpc) {
+		if jsonrpc, _, err = readBinaryString(scanner, members.jsonrpc); err != nil {
+			return nil, nil, err
+		}
+	}
+	if binaryIsSet(scanner, members.method) {
+		if method, _, err = readBinaryString(scanner, members.method); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var rpcError *RPCError
+	if binaryIsSet(scanner, members.error) {
+		errorObject, err := binaryJSON(scanner, members.error)
+		if err != nil {
+			return nil, nil, err
+		}
+		if rpcError, err = decodeRPCError(errorObject); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if binaryIsSet(scanner, members.id) {
+		id, _, err := readBinaryString(scanner, members.id)
+		if err != nil {
+			return nil, nil, err
+		}
+		result, err := binaryJSON(scanner, members.result)
+		if err != nil {
+			return nil, nil, err
+		}
+		ID := string(id)
+		return &responseMessage{JSONRPC: string(jsonrpc), ID: &ID, Result: result, Error: rpcError}, nil, nil
+	}
+
+	if len(method) > 0 || rpcError != nil {
+		params, err := binaryJSON(scanner, members.params)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &responseMessage{JSONRPC: string(jsonrpc), Error: rpcError, Method: string(method), Params: params}, nil, nil
+	}
+
+	// A result which isn't a map, or null, is an unknown notification, see decodeJSONFrame
+	if item, _, err := scanner.item(members.result); err != nil || item.kind != binaryMap {
+		result, err := binaryJSON(scanner, members.result)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &responseMessage{JSONRPC: string(jsonrpc), Params: result}, nil, nil
+	}
+
+	eventMessage, err := decodeBinaryEvents(scanner, members.result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, eventMessage, nil
+}
+
+// decodeReflectedFrame decodes the frame natively and transcodes only results and event data to JSON.
+func decodeReflectedFrame(codec Codec, message []byte) (*responseMessage, *EventMessage, error) {
+	frame := new(binaryFrame)
+	if err := codec.Unmarshal(message, frame); err != nil {
+		return nil, nil, err
+	}
+
+	errorObject, err := transcode(codec, frame.Error)
+	if err != nil {
+		return nil, nil, err
+	}
+	rpcError, err := decodeRPCError(errorObject)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if frame.ID != nil {
+		result, err := transcode(codec, frame.Result)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &responseMessage{JSONRPC: frame.JSONRPC, ID: frame.ID, Result: result, Error: rpcError}, nil, nil
+	}
+
+	if frame.Method != "" || rpcError != nil {
+		params, err := transcode(codec, frame.Params)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &responseMessage{JSONRPC: frame.JSONRPC, Error: rpcError, Method: frame.Method, Params: params}, nil, nil
+	}
+
+	// Msgpack decodes a null result as a missing one, CBOR keeps it
+	if scanner, ok := codec.(binaryScanner); len(frame.Result) == 0 || ok && !binaryIsSet(scanner, frame.Result) {
+		return &responseMessage{JSONRPC: frame.JSONRPC}, nil, nil
+	}
+
+	wire := new(binaryEventMessage)
+	if err := codec.Unmarshal(frame.Result, wire); err != nil {
+		result, err := transcode(codec, frame.Result)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &responseMessage{JSONRPC: frame.JSONRPC, Params: result}, nil, nil
+	}
+
+	eventMessage := &EventMessage{
+		Offset: wire.Offset,
+		Events: make([]*Event, len(wire.Events)),
+	}
+	for i, event := range wire.Events {
+		data, err := transcode(codec, event.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		eventMessage.Events[i] = &Event{
+			Offset:    event.Offset,
+			Sender:    event.Sender,
+			CasinoID:  event.CasinoID,
+			GameID:    event.GameID,
+			RequestID: event.RequestID,
+			EventType: event.EventType,
+			Data:      data,
+		}
+	}
+	return nil, eventMessage, nil
+}
+
+// decodeGenericFrame decodes a frame of a custom codec by transcoding the whole result to JSON.
+func decodeGenericFrame(codec Codec, message []byte) (*responseMessage, *EventMessage, error) {
+	frame := struct {
+		JSONRPC string      `json:"jsonrpc"`
+		ID      *string     `json:"id"`
+		Result  interface{} `json:"result"`
+		Error   interface{} `json:"error"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params"`
+	}{}
+	if err := codec.Unmarshal(message, &frame); err != nil {
+		return nil, nil, err
+	}
+
+	result, err := json.Marshal(normalize(frame.Result))
+	if err != nil {
+		return nil, nil, err
+	}
+	errorObject, err := json.Marshal(normalize(frame.Error))
+	if err != nil {
+		return nil, nil, err
+	}
+	rpcError, err := decodeRPCError(errorObject)
+	if err != nil {
+		return nil, nil, err
+	}
+	if frame.Result == nil && rpcError != nil {
+		result = nil // a null result can't be told from a missing one, which an error response has
+	}
+
+	if frame.ID != nil {
+		return &responseMessage{JSONRPC: frame.JSONRPC, ID: frame.ID, Result: result, Error: rpcError}, nil, nil
+	}
+
+	if frame.Method != "" || rpcError != nil {
+		params, err := json.Marshal(normalize(frame.Params))
+		if err != nil {
+			return nil, nil, err
+		}
+		return &responseMessage{JSONRPC: frame.JSONRPC, Error: rpcError, Method: frame.Method, Params: params}, nil, nil
+	}
+
+	if frame.Result == nil {
+		return &responseMessage{JSONRPC: frame.JSONRPC, Params: result}, nil, nil
+	}
+
+	eventMessage := new(EventMessage)
+	if err := json.Unmarshal(result, eventMessage); err != nil {
+		return &responseMessage{JSONRPC: frame.JSONRPC, Params: result}, nil, nil
+	}
+	return nil, eventMessage, nil
+}
+
+// transcode converts a raw value of the codec to JSON.
+func transcode(codec Codec, raw []byte) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	if scanner, ok := codec.(binaryScanner); ok {
+		// Malformed values fail on the generic path with the error of the codec
+		if data, rest, err := appendBinaryJSON(scanner, make([]byte, 0, 2*len(raw)), raw); err == nil && len(rest) == 0 {
+			return data, nil
+		}
+	}
+
+	var value interface{}
+	if err := codec.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(normalize(value))
+}
+
+// normalize converts the maps with non-string keys some decoders produce, so the value can be encoded as JSON.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalize(item)
+		}
+		return m
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
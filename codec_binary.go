@@ -0,0 +1,454 @@
+package eventlistener
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var errTranscode = errors.New("value left to the codec")
+
+type binaryKind byte
+
+const (
+	binaryNil binaryKind = iota
+	binaryBool
+	binaryUint
+	binaryInt
+	binaryFloat
+	binaryString
+	binaryArray
+	binaryMap
+)
+
+// binaryItem is the header of a value of a binary codec.
+type binaryItem struct {
+	kind binaryKind
+	n    uint64 // length of strings, arrays and maps, value of booleans and integers, bits of floats as float64
+	bits int    // precision of floats formatted as JSON
+}
+
+// binaryScanner reads the values of a binary codec without decoding them, so frames are decoded
+// without reflection and values transcoded to JSON without intermediate values.
+// item returns the header of the next value and the bytes following it, the contents for strings.
+// Values it leaves to the generic path, e.g. extensions, fail with errTranscode.
+type binaryScanner interface {
+	item(src []byte) (binaryItem, []byte, error)
+}
+
+// bigEndian reads an unsigned integer of the size in bytes.
+func bigEndian(src []byte, size int) (uint64, []byte, error) {
+	if len(src) < size {
+		return 0, nil, errInvalidFrame
+	}
+
+	var n uint64
+	for _, b := range src[:size] {
+		n = n<<8 | uint64(b)
+	}
+	return n, src[size:], nil
+}
+
+// appendBinaryJSON appends the first value of src as JSON and returns the bytes following it.
+func appendBinaryJSON(scanner binaryScanner, dst, src []byte) ([]byte, []byte, error) {
+	item, src, err := scanner.item(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch item.kind {
+	case binaryNil:
+		return append(dst, "null"...), src, nil
+	case binaryBool:
+		return strconv.AppendBool(dst, item.n == 1), src, nil
+	case binaryUint:
+		return strconv.AppendUint(dst, item.n, 10), src, nil
+	case binaryInt:
+		return strconv.AppendInt(dst, int64(item.n), 10), src, nil
+	case binaryFloat:
+		dst, err = appendJSONFloat(dst, math.Float64frombits(item.n), item.bits)
+		return dst, src, err
+	case binaryString:
+		if uint64(len(src)) < item.n {
+			return nil, nil, errInvalidFrame
+		}
+		if !utf8.Valid(src[:item.n]) {
+			return nil, nil, errTranscode // CBOR rejects invalid UTF-8, msgpack replaces it
+		}
+		return appendJSONString(dst, src[:item.n]), src[item.n:], nil
+	case binaryArray:
+		if uint64(len(src)) < item.n {
+			return nil, nil, errInvalidFrame
+		}
+		dst = append(dst, '[')
+		for i := uint64(0); i < item.n; i++ {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			if dst, src, err = appendBinaryJSON(scanner, dst, src); err != nil {
+				return nil, nil, err
+			}
+		}
+		return append(dst, ']'), src, nil
+	default:
+		if uint64(len(src)) < 2*item.n {
+			return nil, nil, errInvalidFrame
+		}
+		dst = append(dst, '{')
+		for i := uint64(0); i < item.n; i++ {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			var key []byte
+			if key, src, err = readBinaryString(scanner, src); err != nil {
+				return nil, nil, err
+			}
+			dst = append(appendJSONString(dst, key), ':')
+			if dst, src, err = appendBinaryJSON(scanner, dst, src); err != nil {
+				return nil, nil, err
+			}
+		}
+		return append(dst, '}'), src, nil
+	}
+}
+
+// skipBinary returns the bytes following the first value of src.
+func skipBinary(scanner binaryScanner, src []byte) ([]byte, error) {
+	item, src, err := scanner.item(src)
+	if err != nil {
+		return nil, err
+	}
+
+	switch item.kind {
+	case binaryString:
+		if uint64(len(src)) < item.n {
+			return nil, errInvalidFrame
+		}
+		return src[item.n:], nil
+	case binaryArray, binaryMap:
+		n := item.n
+		if item.kind == binaryMap {
+			n *= 2
+		}
+		if uint64(len(src)) < n {
+			return nil, errInvalidFrame
+		}
+		for i := uint64(0); i < n; i++ {
+			if src, err = skipBinary(scanner, src); err != nil {
+				return nil, err
+			}
+		}
+		return src, nil
+	default:
+		return src, nil
+	}
+}
+
+// readBinaryString reads a string, other values fail with errTranscode.
+func readBinaryString(scanner binaryScanner, src []byte) ([]byte, []byte, error) {
+	item, src, err := scanner.item(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	if item.kind != binaryString {
+		return nil, nil, errTranscode
+	}
+	if uint64(len(src)) < item.n {
+		return nil, nil, errInvalidFrame
+	}
+	if !utf8.Valid(src[:item.n]) {
+		return nil, nil, errTranscode
+	}
+	return src[:item.n], src[item.n:], nil
+}
+
+// readBinaryUint reads an unsigned integer, other values fail with errTranscode.
+func readBinaryUint(scanner binaryScanner, src []byte) (uint64, []byte, error) {
+	item, src, err := scanner.item(src)
+	if err != nil {
+		return 0, nil, err
+	}
+	if item.kind != binaryUint {
+		return 0, nil, errTranscode
+	}
+	return item.n, src, nil
+}
+
+// readBinaryMap reads the header of a map, other values fail with errTranscode.
+func readBinaryMap(scanner binaryScanner, src []byte) (uint64, []byte, error) {
+	item, src, err := scanner.item(src)
+	if err != nil {
+		return 0, nil, err
+	}
+	if item.kind != binaryMap {
+		return 0, nil, errTranscode
+	}
+	if uint64(len(src)) < 2*item.n {
+		return 0, nil, errInvalidFrame
+	}
+	return item.n, src, nil
+}
+
+// scanBinaryFrame finds the top-level members of a binary frame without decoding them, like scanFrame.
+func scanBinaryFrame(scanner binaryScanner, src []byte) (frameMembers, error) {
+	var members frameMembers
+
+	n, src, err := readBinaryMap(scanner, src)
+	if err != nil {
+		return members, err
+	}
+
+	for i := uint64(0); i < n; i++ {
+		var key, value []byte
+		if key, src, err = readBinaryString(scanner, src); err != nil {
+			return members, err
+		}
+
+		value = src
+		if src, err = skipBinary(scanner, src); err != nil {
+			return members, err
+		}
+		value = value[:len(value)-len(src)]
+
+		var member *[]byte
+		switch string(key) {
+		case "jsonrpc":
+			member = &members.jsonrpc
+		case "id":
+			member = &members.id
+		case "result":
+			member = &members.result
+		case "error":
+			member = &members.error
+		case "method":
+			member = &members.method
+		case "params":
+			member = &members.params
+		default:
+			if foldedKey(key, frameKeys) {
+				return members, errTranscode // CBOR matches the members case-insensitively
+			}
+			continue
+		}
+
+		// The codecs decode every occurrence of a member, so do they fail on an invalid one
+		if len(*member) > 0 {
+			return members, errTranscode
+		}
+		*member = value
+	}
+
+	// The codecs differ on trailing data, leave it to them
+	if len(src) > 0 {
+		return members, errTranscode
+	}
+	return members, nil
+}
+
+var (
+	frameKeys        = []string{"jsonrpc", "id", "result", "error", "method", "params"}
+	eventMessageKeys = []string{"offset", "events"}
+	eventKeys        = []string{"offset", "sender", "casino_id", "game_id", "req_id", "event_type", "data"}
+)
+
+// foldedKey reports whether the key is one of the keys in another case.
+func foldedKey(key []byte, keys []string) bool {
+	for _, k := range keys {
+		if len(key) == len(k) && strings.EqualFold(string(key), k) {
+			return true
+		}
+	}
+	return false
+}
+
+// binaryIsSet reports whether a scanned member is present and not nil.
+func binaryIsSet(scanner binaryScanner, member []byte) bool {
+	if len(member) == 0 {
+		return false
+	}
+	item, _, err := scanner.item(member)
+	return err != nil || item.kind != binaryNil
+}
+
+// binaryJSON transcodes a scanned member to JSON, nil if it is absent.
+func binaryJSON(scanner binaryScanner, member []byte) (json.RawMessage, error) {
+	if len(member) == 0 {
+		return nil, nil
+	}
+	data, _, err := appendBinaryJSON(scanner, make([]byte, 0, 2*len(member)), member)
+	return data, err
+}
+
+// decodeBinaryEvents decodes an event batch into a pooled message, event data straight into JSON.
+func decodeBinaryEvents(scanner binaryScanner, src []byte) (*EventMessage, error) {
+	message := acquireEventMessage()
+	if err := message.decodeBinary(scanner, src); err != nil {
+		message.pooled = true
+		message.Release()
+		return nil, err
+	}
+	return message, nil
+}
+
+func (m *EventMessage) decodeBinary(scanner binaryScanner, src []byte) error {
+	n, src, err := readBinaryMap(scanner, src)
+	if err != nil {
+		return err
+	}
+
+	for i := uint64(0); i < n; i++ {
+		var key []byte
+		if key, src, err = readBinaryString(scanner, src); err != nil {
+			return err
+		}
+
+		switch string(key) {
+		case "offset":
+			m.Offset, src, err = readBinaryUint(scanner, src)
+		case "events":
+			src, err = m.decodeBinaryEvents(scanner, src)
+		default:
+			if foldedKey(key, eventMessageKeys) {
+				return errTranscode
+			}
+			src, err = skipBinary(scanner, src)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *EventMessage) decodeBinaryEvents(scanner binaryScanner, src []byte) ([]byte, error) {
+	item, src, err := scanner.item(src)
+	if err != nil {
+		return nil, err
+	}
+	if item.kind == binaryNil {
+		return src, nil
+	}
+	if item.kind != binaryArray || uint64(len(src)) < item.n {
+		return nil, errTranscode
+	}
+
+	// Reuse the events of a pooled message, like the JSON decoder
+	all := m.Events[:cap(m.Events)]
+	m.Events = m.Events[:0]
+	for i := uint64(0); i < item.n; i++ {
+		var event *Event
+		if int(i) < len(all) && all[i] != nil {
+			event = all[i]
+		} else {
+			event = new(Event)
+		}
+		m.Events = append(m.Events, event)
+
+		// Events of a batch mostly share the sender, so do their strings
+		previous := ""
+		if i > 0 {
+			previous = m.Events[i-1].Sender
+		}
+		if src, err = event.decodeBinary(scanner, src, previous); err != nil {
+			return nil, err
+		}
+	}
+	return src, nil
+}
+
+func (event *Event) decodeBinary(scanner binaryScanner, src []byte, sender string) ([]byte, error) {
+	n, src, err := readBinaryMap(scanner, src)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i < n; i++ {
+		var key, value []byte
+		if key, src, err = readBinaryString(scanner, src); err != nil {
+			return nil, err
+		}
+
+		switch string(key) {
+		case "offset":
+			event.Offset, src, err = readBinaryUint(scanner, src)
+		case "casino_id":
+			event.CasinoID, src, err = readBinaryUint(scanner, src)
+		case "game_id":
+			event.GameID, src, err = readBinaryUint(scanner, src)
+		case "req_id":
+			event.RequestID, src, err = readBinaryUint(scanner, src)
+		case "event_type":
+			var eventType uint64
+			eventType, src, err = readBinaryUint(scanner, src)
+			event.EventType = EventType(eventType)
+		case "sender":
+			if value, src, err = readBinaryString(scanner, src); err == nil {
+				if string(value) != sender {
+					sender = string(value)
+				}
+				event.Sender = sender
+			}
+		case "data":
+			// Size a new buffer once, JSON is seldom twice as long as the encoded value
+			if cap(event.Data) == 0 {
+				var rest []byte
+				if rest, err = skipBinary(scanner, src); err != nil {
+					return nil, err
+				}
+				event.Data = make([]byte, 0, 2*(len(src)-len(rest)))
+			}
+			event.Data, src, err = appendBinaryJSON(scanner, event.Data[:0], src)
+		default:
+			if foldedKey(key, eventKeys) {
+				return nil, errTranscode
+			}
+			src, err = skipBinary(scanner, src)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return src, nil
+}
+
+// appendJSONString appends the string quoted as JSON.
+func appendJSONString(dst []byte, s []byte) []byte {
+	for _, c := range s {
+		if c < 0x20 || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' || c >= utf8.RuneSelf {
+			quoted, _ := json.Marshal(string(s)) // escapes like encoding/json, a string can't fail
+			return append(dst, quoted...)
+		}
+	}
+
+	dst = append(dst, '"')
+	dst = append(dst, s...)
+	return append(dst, '"')
+}
+
+// appendJSONFloat appends the float formatted like encoding/json, it fails on NaN and infinities.
+func appendJSONFloat(dst []byte, f float64, bits int) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, errTranscode
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	start := len(dst)
+	dst = strconv.AppendFloat(dst, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		if n := len(dst) - start; n >= 4 && dst[len(dst)-4] == 'e' && dst[len(dst)-3] == '-' && dst[len(dst)-2] == '0' {
+			dst[len(dst)-2] = dst[len(dst)-1]
+			dst = dst[:len(dst)-1]
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,123 @@
+package eventlistener
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gorilla/websocket"
+	"math"
+	"reflect"
+)
+
+const cborSubprotocol = "cbor"
+
+var (
+	cborEncoding, _ = cbor.EncOptions{}.EncMode()
+	cborDecoding, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}(nil))}.DecMode()
+)
+
+type cborCodec struct{}
+
+func (cborCodec) Subprotocol() string {
+	return cborSubprotocol
+}
+
+func (cborCodec) FrameType() int {
+	return websocket.BinaryMessage
+}
+
+func (cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return cborEncoding.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, v interface{}) error {
+	return cborDecoding.Unmarshal(data, v)
+}
+
+func (r *binaryRaw) UnmarshalCBOR(data []byte) error {
+	*r = append((*r)[:0], data...)
+	return nil
+}
+
+// item reads the header of a CBOR data item. Byte strings, tags and indefinite lengths are left to the generic path.
+func (cborCodec) item(src []byte) (binaryItem, []byte, error) {
+	if len(src) == 0 {
+		return binaryItem{}, nil, errInvalidFrame
+	}
+	major, info, src := src[0]>>5, src[0]&0x1f, src[1:]
+
+	if major == 7 {
+		switch info {
+		case 20, 21:
+			return binaryItem{kind: binaryBool, n: uint64(info - 20)}, src, nil
+		case 22, 23:
+			return binaryItem{kind: binaryNil}, src, nil
+		}
+	}
+
+	n := uint64(info)
+	if info >= 24 {
+		if info > 27 {
+			return binaryItem{}, nil, errTranscode
+		}
+
+		var err error
+		if n, src, err = bigEndian(src, 1<<(info-24)); err != nil {
+			return binaryItem{}, nil, err
+		}
+	}
+
+	switch major {
+	case 0:
+		return binaryItem{kind: binaryUint, n: n}, src, nil
+	case 1:
+		if n > math.MaxInt64 {
+			return binaryItem{}, nil, errTranscode
+		}
+		return binaryItem{kind: binaryInt, n: uint64(-1 - int64(n))}, src, nil
+	case 3:
+		return binaryItem{kind: binaryString, n: n}, src, nil
+	case 4:
+		return binaryItem{kind: binaryArray, n: n}, src, nil
+	case 5:
+		return binaryItem{kind: binaryMap, n: n}, src, nil
+	case 7:
+		// Floats decode to float64 on the generic path
+		var f float64
+		switch info {
+		case 25:
+			f = halfFloat(uint16(n))
+		case 26:
+			f = float64(math.Float32frombits(uint32(n)))
+		case 27:
+			f = math.Float64frombits(n)
+		default:
+			return binaryItem{}, nil, errTranscode
+		}
+		return binaryItem{kind: binaryFloat, n: math.Float64bits(f), bits: 64}, src, nil
+	default:
+		return binaryItem{}, nil, errTranscode
+	}
+}
+
+// halfFloat converts an IEEE 754 half-precision float.
+func halfFloat(h uint16) float64 {
+	exponent, mantissa := int(h>>10&0x1f), float64(h&0x3ff)
+
+	var f float64
+	switch exponent {
+	case 0:
+		f = math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mantissa+1024, exponent-25)
+	}
+
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
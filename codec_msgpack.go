@@ -0,0 +1,117 @@
+package eventlistener
+
+import (
+	"bytes"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+	"math"
+)
+
+const msgpackSubprotocol = "msgpack"
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Subprotocol() string {
+	return msgpackSubprotocol
+}
+
+func (msgpackCodec) FrameType() int {
+	return websocket.BinaryMessage
+}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	encoder := msgpack.GetEncoder()
+	defer msgpack.PutEncoder(encoder)
+
+	encoder.Reset(&buffer)
+	encoder.SetCustomStructTag("json")
+	encoder.UseCompactInts(true)
+
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	decoder := msgpack.GetDecoder()
+	defer msgpack.PutDecoder(decoder)
+
+	decoder.Reset(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+
+	return decoder.Decode(v)
+}
+
+func (r *binaryRaw) DecodeMsgpack(decoder *msgpack.Decoder) error {
+	raw, err := decoder.DecodeRaw()
+	if err != nil {
+		return err
+	}
+	*r = binaryRaw(raw)
+	return nil
+}
+
+// item reads the header of a msgpack value. Binaries and extensions are left to the generic path.
+func (msgpackCodec) item(src []byte) (binaryItem, []byte, error) {
+	if len(src) == 0 {
+		return binaryItem{}, nil, errInvalidFrame
+	}
+	c, src := src[0], src[1:]
+
+	switch {
+	case c <= 0x7f:
+		return binaryItem{kind: binaryUint, n: uint64(c)}, src, nil
+	case c >= 0xe0:
+		return binaryItem{kind: binaryInt, n: uint64(int64(int8(c)))}, src, nil
+	case c&0xf0 == 0x80:
+		return binaryItem{kind: binaryMap, n: uint64(c & 0x0f)}, src, nil
+	case c&0xf0 == 0x90:
+		return binaryItem{kind: binaryArray, n: uint64(c & 0x0f)}, src, nil
+	case c&0xe0 == 0xa0:
+		return binaryItem{kind: binaryString, n: uint64(c & 0x1f)}, src, nil
+	}
+
+	var item binaryItem
+	size := 0
+	switch c {
+	case 0xc0:
+		return binaryItem{kind: binaryNil}, src, nil
+	case 0xc2, 0xc3:
+		return binaryItem{kind: binaryBool, n: uint64(c - 0xc2)}, src, nil
+	case 0xca, 0xcb:
+		item.kind, size = binaryFloat, 4<<(c-0xca)
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		item.kind, size = binaryUint, 1<<(c-0xcc)
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		item.kind, size = binaryInt, 1<<(c-0xd0)
+	case 0xd9, 0xda, 0xdb:
+		item.kind, size = binaryString, 1<<(c-0xd9)
+	case 0xdc, 0xdd:
+		item.kind, size = binaryArray, 2<<(c-0xdc)
+	case 0xde, 0xdf:
+		item.kind, size = binaryMap, 2<<(c-0xde)
+	default:
+		return binaryItem{}, nil, errTranscode
+	}
+
+	n, src, err := bigEndian(src, size)
+	if err != nil {
+		return binaryItem{}, nil, err
+	}
+	item.n = n
+
+	switch {
+	case item.kind == binaryFloat && size == 4:
+		item.n, item.bits = math.Float64bits(float64(math.Float32frombits(uint32(n)))), 32
+	case item.kind == binaryFloat:
+		item.bits = 64
+	case item.kind == binaryInt:
+		// Sign-extend the two's complement
+		shift := uint(64 - 8*size)
+		item.n = uint64(int64(n<<shift) >> shift)
+	}
+	return item, src, nil
+}
@@ -0,0 +1,315 @@
+package eventlistener
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// textCodec is a custom codec, JSON negotiated under its own subprotocol.
+type textCodec struct {
+	jsonCodec
+}
+
+func (textCodec) Subprotocol() string {
+	return "text"
+}
+
+func newCodecListener(t *testing.T, server *testServer, codec Codec, event chan<- *EventMessage) *EventListener {
+	parentContext, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	listener := NewEventListener(server.addr(), event)
+	listener.Codec = codec
+	require.NoError(t, listener.ListenAndServe(parentContext))
+	return listener
+}
+
+func TestCodec_Subscribe(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec, CBORCodec, textCodec{}} {
+		codec := codec
+		t.Run(fmt.Sprintf("%T", codec), func(t *testing.T) {
+			server := newTestServer(t)
+			server.codecs = append(server.codecs, codec)
+			server.publish(1, `{"amount":5,"player":"alice","bets":[1.5,2],"meta":null}`)
+
+			events := make(chan *EventMessage, 1)
+			listener := newCodecListener(t, server, codec, events)
+			defer listener.Close()
+			assert.Equal(t, codec, listener.wireCodec())
+
+			ok, err := listener.Subscribe(1, offset)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			event := receiveEvent(t, events)
+			assert.Equal(t, EventType(1), event.EventType)
+			assert.JSONEq(t, `{"amount":5,"player":"alice","bets":[1.5,2],"meta":null}`, string(event.Data))
+
+			_, err = listener.Unsubscribe(2)
+			assert.EqualError(t, err, "topic not subscribed")
+		})
+	}
+}
+
+func TestCodec_fallback(t *testing.T) {
+	server := newTestServer(t)
+	server.codecs = nil
+	server.publish(1, `{"amount":5}`)
+
+	events := make(chan *EventMessage, 1)
+	listener := newCodecListener(t, server, MsgpackCodec, events)
+	defer listener.Close()
+	assert.Equal(t, JSONCodec, listener.wireCodec())
+
+	ok, err := listener.Subscribe(1, offset)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"amount":5}`, string(receiveEvent(t, events).Data))
+}
+
+func TestBinaryScanner(t *testing.T) {
+	values := []interface{}{
+		nil, true, false, 0, 127, 128, -1, -32, -33, -129, -40000, int64(-1) << 40, uint64(1<<64 - 1),
+		1.5, -0.25, 1e-7, 1e21, float32(0.1), "", "plain", `quote " and \ <tag> & é`, []interface{}{},
+		map[string]interface{}{"a": []interface{}{1, "x", nil, map[string]interface{}{"b": 2.5}}},
+		strings.Repeat("long ", 100), make([]interface{}, 20),
+	}
+
+	for _, codec := range []Codec{MsgpackCodec, CBORCodec} {
+		scanner := codec.(binaryScanner)
+		for _, value := range values {
+			raw, err := codec.Marshal(value)
+			require.NoError(t, err)
+
+			data, rest, err := appendBinaryJSON(scanner, nil, raw)
+			require.NoError(t, err, "%T %v", codec, value)
+			assert.Empty(t, rest)
+
+			var generic interface{}
+			require.NoError(t, codec.Unmarshal(raw, &generic))
+			expected, err := json.Marshal(normalize(generic))
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(data), "%T %v", codec, value)
+		}
+	}
+
+	// Values left to the codec are still transcoded
+	for _, left := range []struct {
+		codec Codec
+		value interface{}
+	}{
+		{MsgpackCodec, time.Unix(0, 0).UTC()},
+		{MsgpackCodec, []byte{1, 2}},
+		{CBORCodec, []byte{1, 2}},
+	} {
+		codec := left.codec
+		raw, err := codec.Marshal(left.value)
+		require.NoError(t, err)
+
+		_, _, err = appendBinaryJSON(codec.(binaryScanner), nil, raw)
+		assert.Equal(t, errTranscode, err)
+		_, err = transcode(codec, raw)
+		assert.NoError(t, err)
+	}
+}
+
+// binaryFrames are frames of every kind, for the binary codecs.
+var binaryFrames = []interface{}{
+	map[string]interface{}{"id": "1", "result": map[string]interface{}{"topics": []string{"event_1"}}},
+	map[string]interface{}{"id": "2", "error": map[string]interface{}{"code": -1, "message": "x", "data": 1}},
+	map[string]interface{}{"method": NotificationTokenExpired, "params": nil},
+	map[string]interface{}{"result": "maintenance"},
+	map[string]interface{}{"result": nil},
+	map[string]interface{}{"id": nil, "result": map[string]interface{}{"offset": 7, "events": []interface{}{
+		map[string]interface{}{"offset": 6, "sender": "a", "casino_id": 1, "game_id": 2, "req_id": 3, "event_type": 4, "data": map[string]interface{}{"x": []int{1}}},
+		map[string]interface{}{"offset": 7, "sender": "a", "unknown": true},
+	}}},
+}
+
+// truncatedMaps are maps of one entry without it.
+var truncatedMaps = map[Codec][]byte{MsgpackCodec: {0x81}, CBORCodec: {0xa1}}
+
+func TestDecodeBinaryFrame_scanned(t *testing.T) {
+	for _, codec := range []Codec{MsgpackCodec, CBORCodec} {
+		for _, frame := range binaryFrames {
+			message, err := codec.Marshal(frame)
+			require.NoError(t, err)
+
+			response, eventMessage, err := decodeScannedFrame(codec.(binaryScanner), message)
+			require.NoError(t, err)
+			expectedResponse, expectedMessage, err := decodeReflectedFrame(codec, message)
+			require.NoError(t, err)
+			assertSameFrame(t, expectedResponse, expectedMessage, response, eventMessage)
+		}
+
+		// Malformed frames fail once with the error of the scanner, extensions are decoded by reflection
+		counters := new(counters)
+		_, _, err := decodeFrame(codec, truncatedMaps[codec], counters)
+		assert.Error(t, err)
+		assert.Equal(t, uint64(0), counters.decodeFallbacks)
+
+		message, err := codec.Marshal(map[string]interface{}{"result": map[string]interface{}{"events": []interface{}{
+			map[string]interface{}{"data": []byte{1}},
+		}}})
+		require.NoError(t, err)
+		_, eventMessage, err := decodeFrame(codec, message, counters)
+		require.NoError(t, err)
+		assert.Len(t, eventMessage.Events, 1)
+		assert.Equal(t, uint64(1), counters.decodeFallbacks)
+	}
+}
+
+// FuzzDecodeBinaryFrame checks the scanned frames against the frames decoded by reflection:
+// a frame the scanner accepts decodes alike, one it rejects as malformed fails on the codec too.
+func FuzzDecodeBinaryFrame(f *testing.F) {
+	for _, frame := range binaryFrames {
+		for i, codec := range []Codec{MsgpackCodec, CBORCodec} {
+			message, err := codec.Marshal(frame)
+			require.NoError(f, err)
+			f.Add(message, i == 1)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, message []byte, cbor bool) {
+		codec := MsgpackCodec
+		if cbor {
+			codec = CBORCodec
+		}
+
+		response, eventMessage, err := decodeScannedFrame(codec.(binaryScanner), message)
+		if err == errTranscode {
+			return
+		}
+		expectedResponse, expectedMessage, expectedErr := decodeReflectedFrame(codec, message)
+		if err != nil {
+			assert.Error(t, expectedErr, "scanner: %v", err)
+			return
+		}
+		require.NoError(t, expectedErr)
+		assertSameFrame(t, expectedResponse, expectedMessage, response, eventMessage)
+	})
+}
+
+// assertSameFrame compares a scanned frame to the one decoded by reflection.
+func assertSameFrame(t *testing.T, expectedResponse *responseMessage, expectedMessage *EventMessage, response *responseMessage, eventMessage *EventMessage) {
+	// The reflected frame drops nil members, the scanned one keeps them null like JSON frames
+	if expectedResponse != nil {
+		require.NotNil(t, response)
+		assert.Equal(t, expectedResponse.JSONRPC, response.JSONRPC)
+		assert.Equal(t, expectedResponse.ID, response.ID)
+		assert.Equal(t, expectedResponse.Error, response.Error)
+		assert.Equal(t, expectedResponse.Method, response.Method)
+		assert.Equal(t, nullJSON(expectedResponse.Result), nullJSON(response.Result))
+		assert.Equal(t, nullJSON(expectedResponse.Params), nullJSON(response.Params))
+	} else {
+		assert.Nil(t, response)
+	}
+
+	if expectedMessage != nil {
+		require.NotNil(t, eventMessage)
+		assert.Equal(t, expectedMessage.Offset, eventMessage.Offset)
+		require.Len(t, eventMessage.Events, len(expectedMessage.Events))
+		for i, event := range eventMessage.Events {
+			expected := *expectedMessage.Events[i]
+			actual := *event
+			expected.Data, actual.Data = nil, nil
+			assert.Equal(t, expected, actual)
+			assert.Equal(t, nullJSON(expectedMessage.Events[i].Data), nullJSON(event.Data))
+		}
+	} else {
+		assert.Nil(t, eventMessage)
+	}
+}
+
+// nullJSON compacts a JSON value, an absent one as null.
+func nullJSON(data json.RawMessage) string {
+	if len(data) == 0 {
+		return "null"
+	}
+	var buffer bytes.Buffer
+	if err := json.Compact(&buffer, data); err != nil {
+		return string(data)
+	}
+	return buffer.String()
+}
+
+func benchmarkFrame(b *testing.B, codec Codec) []byte {
+	events := make([]*Event, 100)
+	for i := range events {
+		events[i] = &Event{
+			Offset:    uint64(i),
+			Sender:    "casino.daobet",
+			CasinoID:  1,
+			GameID:    2,
+			RequestID: uint64(1000 + i),
+			EventType: 1,
+			Data:      json.RawMessage(`{"amount":1500,"player":"alice","bets":[1,2,3],"deposit":"10.0000 BET"}`),
+		}
+	}
+
+	frame := struct {
+		Result *EventMessage `json:"result"`
+	}{&EventMessage{Offset: 99, Events: events}}
+
+	if codec == JSONCodec {
+		message, err := json.Marshal(frame)
+		require.NoError(b, err)
+		return message
+	}
+
+	message, err := encodeFrame(codec, frame)
+	require.NoError(b, err)
+	return message
+}
+
+func BenchmarkDecodeFrame(b *testing.B) {
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec, CBORCodec} {
+		codec := codec
+		name := codec.Subprotocol()
+		if name == "" {
+			name = "json"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			message := benchmarkFrame(b, codec)
+			b.SetBytes(int64(len(message)))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, _, err := decodeFrame(codec, message, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeRequest(b *testing.B) {
+	listener := NewEventListener("", nil)
+	request := listener.newSubscribeMessage(1, offset)
+
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec, CBORCodec} {
+		codec := codec
+		name := codec.Subprotocol()
+		if name == "" {
+			name = "json"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := request.encode(codec, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package eventlistener
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration read from configuration as a string like "10s" or a number of nanoseconds.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return d.set(value)
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value interface{}
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	return d.set(value)
+}
+
+func (d *Duration) set(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		duration, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(duration)
+	case float64:
+		*d = Duration(v)
+	case int:
+		*d = Duration(v)
+	default:
+		return fmt.Errorf("invalid duration %v", value)
+	}
+	return nil
+}
+
+// Config holds the settings of a listener, see NewEventListenerFromConfig.
+// Start from DefaultConfig, then load a file, the environment and flags, in the order of precedence.
+// Keys in files are the json/yaml tags, environment variables are the upper-cased keys with a prefix,
+// e.g. ACTION_MONITOR_PONG_WAIT, flags are the keys with dashes, e.g. -pong-wait.
+type Config struct {
+	Name  string `json:"name" yaml:"name"`
+	Addr  string `json:"addr" yaml:"addr"`
+	Token string `json:"token" yaml:"token"`
+
+	MessageSizeLimit int64    `json:"message_size_limit" yaml:"message_size_limit"`
+	WriteWait        Duration `json:"write_wait" yaml:"write_wait"`
+	PongWait         Duration `json:"pong_wait" yaml:"pong_wait"`
+	PingPeriod       Duration `json:"ping_period" yaml:"ping_period"`
+	ResponseWait     Duration `json:"response_wait" yaml:"response_wait"`
+	MaxInFlight      int      `json:"max_in_flight" yaml:"max_in_flight"`
+	RangeIdleWait    Duration `json:"range_idle_wait" yaml:"range_idle_wait"`
+
+	ReconnectionDelay    Duration `json:"reconnection_delay" yaml:"reconnection_delay"`
+	ReconnectionAttempts int      `json:"reconnection_attempts" yaml:"reconnection_attempts"`
+
+	Codec             string `json:"codec" yaml:"codec"` // json, msgpack or cbor
+	EnableCompression bool   `json:"enable_compression" yaml:"enable_compression"`
+	CompressionLevel  int    `json:"compression_level" yaml:"compression_level"`
+	StrictJSONRPC     bool   `json:"strict_jsonrpc" yaml:"strict_jsonrpc"`
+
+	BatchSize        int  `json:"batch_size" yaml:"batch_size"`
+	OversizeRecovery bool `json:"oversize_recovery" yaml:"oversize_recovery"`
+
+	RedactKeys []string `json:"redact_keys" yaml:"redact_keys"`
+
+	Sinks []SinkConfig `json:"sinks" yaml:"sinks"` // Files only.
+}
+
+// DefaultConfig returns the defaults of NewEventListener.
+func DefaultConfig() Config {
+	return Config{
+		MessageSizeLimit:     messageSizeLimit,
+		WriteWait:            Duration(writeWait),
+		PongWait:             Duration(pongWait),
+		PingPeriod:           Duration(pingPeriod),
+		ResponseWait:         Duration(responseWait),
+		MaxInFlight:          maxInFlight,
+		RangeIdleWait:        Duration(rangeIdleWait),
+		ReconnectionDelay:    Duration(reconnectionDelay),
+		ReconnectionAttempts: reconnectionAttempts,
+		Codec:                "json",
+		CompressionLevel:     compressionLevel,
+	}
+}
+
+var codecs = map[string]Codec{
+	"":        JSONCodec,
+	"json":    JSONCodec,
+	"msgpack": MsgpackCodec,
+	"cbor":    CBORCodec,
+}
+
+// Validate checks the settings are consistent.
+func (c *Config) Validate() error {
+	var errs []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Addr != "", "addr is required")
+	check(c.MessageSizeLimit >= 0, "message_size_limit must not be negative")
+	check(c.WriteWait > 0, "write_wait must be positive")
+	check(c.PongWait > 0, "pong_wait must be positive")
+	check(c.PingPeriod > 0, "ping_period must be positive")
+	check(c.PingPeriod < c.PongWait, "ping_period %s must be less than pong_wait %s", c.PingPeriod, c.PongWait)
+	check(c.ResponseWait > 0, "response_wait must be positive")
+	check(c.MaxInFlight > 0, "max_in_flight must be positive")
+	check(c.RangeIdleWait > 0, "range_idle_wait must be positive")
+	check(c.ReconnectionDelay >= 0, "reconnection_delay must not be negative")
+	check(c.ReconnectionAttempts >= 0, "reconnection_attempts must not be negative")
+	_, ok := codecs[c.Codec]
+	check(ok, "unknown codec %q", c.Codec)
+	check(c.CompressionLevel >= -2 && c.CompressionLevel <= 9, "compression_level %d out of range -2..9", c.CompressionLevel)
+	check(c.BatchSize >= 0, "batch_size must not be negative")
+
+	if len(errs) > 0 {
+		return errors.New("invalid config: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// LoadFile reads the settings present in a YAML or JSON file, by extension.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, c)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	default:
+		return fmt.Errorf("config %s: unknown format", path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadEnv reads the settings set in the environment, named prefix_KEY, e.g. ACTION_MONITOR_ADDR.
+// Lists are comma separated.
+func (c *Config) LoadEnv(prefix string) error {
+	for _, field := range c.fields() {
+		name := strings.ToUpper(field.key)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := field.Set(value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RegisterFlags defines a flag per setting, named prefix-key, e.g. -pong-wait. Lists are comma separated.
+// The flags default to the current settings, so register them after loading the file and the environment.
+func (c *Config) RegisterFlags(flags *flag.FlagSet, prefix string) {
+	for _, field := range c.fields() {
+		name := strings.ReplaceAll(field.key, "_", "-")
+		if prefix != "" {
+			name = prefix + "-" + name
+		}
+		flags.Var(field, name, field.key)
+	}
+}
+
+// configField binds a setting to an environment variable or a flag.
+type configField struct {
+	key   string
+	value reflect.Value
+}
+
+// fields returns the settings which can be set from a string.
+func (c *Config) fields() []*configField {
+	value := reflect.ValueOf(c).Elem()
+	fields := make([]*configField, 0, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.String {
+			continue
+		}
+
+		key := strings.Split(value.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		fields = append(fields, &configField{key: key, value: field})
+	}
+	return fields
+}
+
+func (f *configField) String() string {
+	if !f.value.IsValid() {
+		return ""
+	}
+	if f.value.Kind() == reflect.Slice {
+		return strings.Join(f.value.Interface().([]string), ",")
+	}
+	// Flag usage prints the defaults, which may come from the environment
+	if f.key == "token" && f.value.String() != "" {
+		return redacted
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+func (f *configField) Set(s string) error {
+	switch v := f.value.Addr().Interface().(type) {
+	case *Duration:
+		duration, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*v = Duration(duration)
+	case *string:
+		*v = s
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*v = b
+	case *int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*v = n
+	case *int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		*v = n
+	case *[]string:
+		*v = nil
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*v = append(*v, item)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported setting %s", f.key)
+	}
+	return nil
+}
+
+// IsBoolFlag allows -enable-compression without a value.
+func (f *configField) IsBoolFlag() bool {
+	return f.value.IsValid() && f.value.Kind() == reflect.Bool
+}
+
+// NewEventListenerFromConfig validates the config and creates a listener with its sinks.
+// The options are applied on top of the config, e.g. WithLogger, and the sinks added afterwards
+// so they log with the final logger.
+func NewEventListenerFromConfig(config Config, event chan<- *EventMessage, opts ...Option) (*EventListener, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	listener := NewEventListener(config.Addr, event)
+	if config.Name != "" {
+		listener.Name = config.Name
+	}
+	listener.Token = config.Token
+	listener.MessageSizeLimit = config.MessageSizeLimit
+	listener.WriteWait = time.Duration(config.WriteWait)
+	listener.PongWait = time.Duration(config.PongWait)
+	listener.PingPeriod = time.Duration(config.PingPeriod)
+	listener.ResponseWait = time.Duration(config.ResponseWait)
+	listener.MaxInFlight = config.MaxInFlight
+	listener.RangeIdleWait = time.Duration(config.RangeIdleWait)
+	listener.ReconnectionDelay = time.Duration(config.ReconnectionDelay)
+	listener.ReconnectionAttempts = config.ReconnectionAttempts
+	listener.Codec = codecs[config.Codec]
+	listener.EnableCompression = config.EnableCompression
+	listener.CompressionLevel = config.CompressionLevel
+	listener.StrictJSONRPC = config.StrictJSONRPC
+	listener.BatchSize = config.BatchSize
+	listener.OversizeRecovery = config.OversizeRecovery
+	listener.RedactKeys = config.RedactKeys
+
+	sinks := make([]Sink, 0, len(config.Sinks))
+	for _, sinkConfig := range config.Sinks {
+		sink, err := NewSink(sinkConfig)
+		if err != nil {
+			for _, sink := range sinks {
+				_ = sink.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	options := make([]Option, 0, len(opts)+len(sinks))
+	options = append(options, opts...)
+	for _, sink := range sinks {
+		options = append(options, WithSink(sink))
+	}
+	if err := listener.Apply(options...); err != nil {
+		return nil, err
+	}
+
+	return listener, nil
+}
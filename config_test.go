@@ -0,0 +1,142 @@
+package eventlistener
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	return path
+}
+
+func TestConfig_Validate(t *testing.T) {
+	config := DefaultConfig()
+	assert.EqualError(t, config.Validate(), "invalid config: addr is required")
+
+	config.Addr = ":8888"
+	assert.NoError(t, config.Validate())
+
+	config.PingPeriod = config.PongWait
+	config.Codec = "xml"
+	assert.EqualError(t, config.Validate(), "invalid config: ping_period 1m0s must be less than pong_wait 1m0s; unknown codec \"xml\"")
+}
+
+func TestConfig_LoadFile(t *testing.T) {
+	yamlPath := writeConfig(t, "config.yaml", `
+addr: monitor:8888
+pong_wait: 30s
+ping_period: 20000000000
+redact_keys: [seed]
+sinks:
+  - type: webhook
+    url: http://hooks
+    retry_delay: 250ms
+`)
+	jsonPath := writeConfig(t, "config.json", `{"addr":"monitor:8888","pong_wait":"30s","ping_period":20000000000,"redact_keys":["seed"],
+		"sinks":[{"type":"webhook","url":"http://hooks","retry_delay":"250ms"}]}`)
+
+	for _, path := range []string{yamlPath, jsonPath} {
+		config := DefaultConfig()
+		require.NoError(t, config.LoadFile(path), path)
+		assert.Equal(t, "monitor:8888", config.Addr)
+		assert.Equal(t, Duration(30*time.Second), config.PongWait)
+		assert.Equal(t, Duration(20*time.Second), config.PingPeriod)
+		assert.Equal(t, Duration(writeWait), config.WriteWait)
+		assert.Equal(t, []string{"seed"}, config.RedactKeys)
+		require.Len(t, config.Sinks, 1)
+		assert.Equal(t, Duration(250*time.Millisecond), config.Sinks[0].RetryDelay)
+		assert.NoError(t, config.Validate())
+	}
+
+	config := DefaultConfig()
+	assert.Error(t, config.LoadFile(writeConfig(t, "config.yaml", "adr: x")))
+	assert.Error(t, config.LoadFile(writeConfig(t, "config.json", `{"pong_wait":"soon"}`)))
+	assert.Error(t, config.LoadFile(writeConfig(t, "config.toml", "")))
+}
+
+func TestConfig_LoadEnv(t *testing.T) {
+	for name, value := range map[string]string{
+		"TEST_MONITOR_ADDR":               "monitor:8888",
+		"TEST_MONITOR_RESPONSE_WAIT":      "3s",
+		"TEST_MONITOR_ENABLE_COMPRESSION": "true",
+		"TEST_MONITOR_MESSAGE_SIZE_LIMIT": "1024",
+		"TEST_MONITOR_REDACT_KEYS":        "seed, secret",
+	} {
+		require.NoError(t, os.Setenv(name, value))
+		defer os.Unsetenv(name)
+	}
+
+	config := DefaultConfig()
+	require.NoError(t, config.LoadEnv("TEST_MONITOR"))
+	assert.Equal(t, "monitor:8888", config.Addr)
+	assert.Equal(t, Duration(3*time.Second), config.ResponseWait)
+	assert.True(t, config.EnableCompression)
+	assert.Equal(t, int64(1024), config.MessageSizeLimit)
+	assert.Equal(t, []string{"seed", "secret"}, config.RedactKeys)
+
+	require.NoError(t, os.Setenv("TEST_MONITOR_BATCH_SIZE", "many"))
+	defer os.Unsetenv("TEST_MONITOR_BATCH_SIZE")
+	assert.EqualError(t, config.LoadEnv("TEST_MONITOR"), `TEST_MONITOR_BATCH_SIZE: strconv.Atoi: parsing "many": invalid syntax`)
+}
+
+func TestConfig_RegisterFlags(t *testing.T) {
+	config := DefaultConfig()
+	config.Token = "secret"
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	config.RegisterFlags(flags, "monitor")
+	require.NoError(t, flags.Parse([]string{"-monitor-addr", "monitor:8888", "-monitor-enable-compression", "-monitor-codec=cbor", "-monitor-reconnection-delay", "5s"}))
+
+	assert.Equal(t, "monitor:8888", config.Addr)
+	assert.True(t, config.EnableCompression)
+	assert.Equal(t, "cbor", config.Codec)
+	assert.Equal(t, Duration(5*time.Second), config.ReconnectionDelay)
+	assert.Equal(t, redacted, flags.Lookup("monitor-token").DefValue)
+	assert.Nil(t, flags.Lookup("monitor-sinks"))
+}
+
+func TestNewEventListenerFromConfig(t *testing.T) {
+	config := DefaultConfig()
+	_, err := NewEventListenerFromConfig(config, nil)
+	assert.Error(t, err)
+
+	config.Addr = "monitor:8888"
+	config.Name = "monitor-1"
+	config.Codec = "msgpack"
+	config.StrictJSONRPC = true
+	config.PongWait = Duration(time.Second)
+	config.PingPeriod = Duration(500 * time.Millisecond)
+	config.Sinks = []SinkConfig{{Type: "file", Path: filepath.Join(t.TempDir(), "events.jsonl")}}
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	listener, err := NewEventListenerFromConfig(config, nil, WithLogger(NewZapLogger(zap.New(core))))
+	require.NoError(t, err)
+	defer listener.Close()
+
+	assert.Equal(t, "monitor-1", listener.Name)
+	assert.Equal(t, "monitor:8888", listener.Addr)
+	assert.Equal(t, MsgpackCodec, listener.Codec)
+	assert.True(t, listener.StrictJSONRPC)
+	assert.Equal(t, time.Second, listener.PongWait)
+	assert.Equal(t, 500*time.Millisecond, listener.PingPeriod)
+	assert.Len(t, listener.sinks, 1)
+
+	// The sink pumps log with the logger of the options
+	assert.Eventually(t, func() bool {
+		return logs.FilterMessage(msgPumpRunning).Len() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	config.Sinks = append(config.Sinks, SinkConfig{Type: "kafka"})
+	_, err = NewEventListenerFromConfig(config, nil)
+	assert.EqualError(t, err, `unknown sink type "kafka"`)
+}
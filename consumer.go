@@ -0,0 +1,233 @@
+package eventlistener
+
+import (
+	"errors"
+	"sync"
+)
+
+var ConsumerClosed = errors.New("consumer closed")
+
+// Consumer is an independent stream of events multiplexed over one EventListener connection.
+// Consumers may subscribe to overlapping event types: the server-side subscription is made
+// by the first consumer of an event type and dropped when the last one unsubscribes.
+type Consumer struct {
+	listener *EventListener
+	event    chan<- *EventMessage
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	sync.RWMutex
+	closed     bool
+	eventTypes map[EventType]struct{}
+}
+
+// NewConsumer creates a consumer which receives events of its subscriptions in event.
+// The channel is closed when the consumer or the listener is closed.
+// Delivery is blocking, so a slow consumer holds back the whole listener.
+func (e *EventListener) NewConsumer(event chan<- *EventMessage) *Consumer {
+	c := &Consumer{
+		listener:   e,
+		event:      event,
+		done:       make(chan struct{}),
+		eventTypes: make(map[EventType]struct{}),
+	}
+
+	e.Lock()
+	e.consumers[c] = struct{}{}
+	e.Unlock()
+
+	return c
+}
+
+// Subscribe adds eventType to the consumer stream. The offset is only used if no other consumer
+// is subscribed to eventType, otherwise the consumer joins the existing subscription.
+func (c *Consumer) Subscribe(eventType EventType, offset uint64) (bool, error) {
+	e := c.listener
+	e.consumerLock.Lock()
+	defer e.consumerLock.Unlock()
+
+	c.RLock()
+	_, subscribed := c.eventTypes[eventType]
+	closed := c.closed
+	c.RUnlock()
+
+	if closed {
+		return false, ConsumerClosed
+	}
+	if subscribed {
+		return true, nil
+	}
+
+	e.Lock()
+	refs, ok := e.refs[eventType]
+	if !ok {
+		_, direct := e.subscriptions[eventType]
+		refs = &subscriptionRefs{owned: !direct}
+	}
+	e.Unlock()
+
+	// Register before subscribing, the server sends history right after the response
+	c.Lock()
+	c.eventTypes[eventType] = struct{}{}
+	c.Unlock()
+
+	if refs.count == 0 && refs.owned {
+		if ok, err := e.Subscribe(eventType, offset); err != nil || !ok {
+			c.Lock()
+			delete(c.eventTypes, eventType)
+			c.Unlock()
+			return ok, err
+		}
+	}
+
+	e.Lock()
+	refs.count++
+	e.refs[eventType] = refs
+	e.Unlock()
+
+	return true, nil
+}
+
+// Unsubscribe removes eventType from the consumer stream.
+// The server-side unsubscribe is sent only when no other consumer uses eventType.
+func (c *Consumer) Unsubscribe(eventType EventType) (bool, error) {
+	e := c.listener
+	e.consumerLock.Lock()
+	defer e.consumerLock.Unlock()
+
+	c.RLock()
+	_, subscribed := c.eventTypes[eventType]
+	c.RUnlock()
+
+	e.Lock()
+	refs, ok := e.refs[eventType]
+	e.Unlock()
+
+	if !subscribed || !ok {
+		return false, nil
+	}
+
+	// Keep the consumer and its reference until the server unsubscribed, a failed unsubscribe leaves them as they were
+	if refs.count == 1 && refs.owned {
+		var err error
+		if ok, err = e.Unsubscribe(eventType); err != nil {
+			return ok, err
+		}
+	}
+
+	c.Lock()
+	delete(c.eventTypes, eventType)
+	c.Unlock()
+
+	e.Lock()
+	refs.count--
+	if refs.count == 0 {
+		delete(e.refs, eventType)
+	}
+	e.Unlock()
+
+	return ok, nil
+}
+
+// EventTypes returns the event types the consumer is subscribed to.
+func (c *Consumer) EventTypes() []EventType {
+	c.RLock()
+	defer c.RUnlock()
+
+	eventTypes := make([]EventType, 0, len(c.eventTypes))
+	for eventType := range c.eventTypes {
+		eventTypes = append(eventTypes, eventType)
+	}
+	return eventTypes
+}
+
+// Close closes the consumer channel and unsubscribes the consumer from all event types.
+func (c *Consumer) Close() error {
+	// Stop delivery first, the read pump must not block on the consumer while unsubscribing
+	c.close()
+
+	var err error
+	for _, eventType := range c.EventTypes() {
+		if _, unsubscribeErr := c.Unsubscribe(eventType); unsubscribeErr != nil && err == nil {
+			err = unsubscribeErr
+		}
+	}
+
+	c.listener.Lock()
+	delete(c.listener.consumers, c)
+	c.listener.Unlock()
+
+	return err
+}
+
+func (c *Consumer) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+
+		c.Lock()
+		c.closed = true
+		if c.event != nil {
+			close(c.event)
+		}
+		c.Unlock()
+	})
+}
+
+func (c *Consumer) deliver(message *EventMessage) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.closed || c.event == nil {
+		return
+	}
+
+	select {
+	case c.event <- message:
+	case <-c.done:
+	case <-c.listener.done:
+	}
+}
+
+type subscriptionRefs struct {
+	count int
+	owned bool // subscribed by consumers, not directly on the listener
+}
+
+// dispatch delivers the events to the consumers subscribed to their event types.
+func (e *EventListener) dispatch(message *EventMessage) {
+	e.Lock()
+	if len(e.consumers) == 0 {
+		e.Unlock()
+		return
+	}
+
+	groups := make(map[*Consumer][]*Event)
+	for c := range e.consumers {
+		c.RLock()
+		for _, event := range message.Events {
+			if _, ok := c.eventTypes[event.EventType]; ok {
+				groups[c] = append(groups[c], event)
+			}
+		}
+		c.RUnlock()
+	}
+	e.Unlock()
+
+	for c, events := range groups {
+		c.deliver(&EventMessage{Offset: events[len(events)-1].Offset, Events: events, ReceivedAt: message.ReceivedAt})
+	}
+}
+
+func (e *EventListener) closeConsumers() {
+	e.Lock()
+	consumers := make([]*Consumer, 0, len(e.consumers))
+	for c := range e.consumers {
+		consumers = append(consumers, c)
+	}
+	e.Unlock()
+
+	for _, c := range consumers {
+		c.close()
+	}
+}
@@ -0,0 +1,153 @@
+package eventlistener
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newTestListener(t *testing.T, server *testServer, event chan<- *EventMessage) *EventListener {
+	parentContext, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	listener := NewEventListener(server.addr(), event)
+	require.NoError(t, listener.ListenAndServe(parentContext))
+	return listener
+}
+
+func receiveEvent(t *testing.T, events <-chan *EventMessage) *Event {
+	select {
+	case message, ok := <-events:
+		require.True(t, ok, "events channel closed")
+		require.NotEmpty(t, message.Events)
+		return message.Events[0]
+	case <-time.After(waitEventsTimeout):
+		t.Fatal("no events")
+	}
+	return nil
+}
+
+func TestConsumer_Subscribe(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+
+	first := make(chan *EventMessage, 1)
+	second := make(chan *EventMessage, 1)
+	c1 := listener.NewConsumer(first)
+	c2 := listener.NewConsumer(second)
+
+	ok, err := c1.Subscribe(1, offset)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c2.Subscribe(1, offset)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = c2.Subscribe(2, offset)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.Equal(t, 2, server.count(methodSubscribe))
+
+	server.publish(1, `"a"`)
+	assert.Equal(t, EventType(1), receiveEvent(t, first).EventType)
+	assert.Equal(t, EventType(1), receiveEvent(t, second).EventType)
+
+	server.publish(2, `"b"`)
+	assert.Equal(t, EventType(2), receiveEvent(t, second).EventType)
+	assert.Empty(t, first)
+}
+
+func TestConsumer_Unsubscribe(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+
+	c1 := listener.NewConsumer(make(chan *EventMessage, 1))
+	c2 := listener.NewConsumer(make(chan *EventMessage, 1))
+
+	_, err := c1.Subscribe(1, offset)
+	require.NoError(t, err)
+	_, err = c2.Subscribe(1, offset)
+	require.NoError(t, err)
+
+	ok, err := c1.Unsubscribe(1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0, server.count(methodUnsubscribe))
+
+	ok, err = c2.Unsubscribe(1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, server.count(methodUnsubscribe))
+
+	ok, err = c2.Unsubscribe(1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestConsumer_Unsubscribe_failed(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+
+	c := listener.NewConsumer(make(chan *EventMessage, 1))
+	_, err := c.Subscribe(1, offset)
+	require.NoError(t, err)
+
+	// The server lost the subscription, its unsubscribe fails
+	server.Lock()
+	for client := range server.clients {
+		client.Lock()
+		delete(client.topics, EventType(1).ToString())
+		client.Unlock()
+	}
+	server.Unlock()
+
+	_, err = c.Unsubscribe(1)
+	assert.EqualError(t, err, "topic not subscribed")
+	assert.Equal(t, []EventType{1}, c.EventTypes())
+
+	listener.Lock()
+	refs := listener.refs[1]
+	listener.Unlock()
+	require.NotNil(t, refs)
+	assert.Equal(t, 1, refs.count)
+}
+
+func TestConsumer_Close(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+
+	events := make(chan *EventMessage)
+	c := listener.NewConsumer(events)
+
+	_, err := c.Subscribe(1, offset)
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.Equal(t, 1, server.count(methodUnsubscribe))
+
+	_, err = c.Subscribe(1, offset)
+	assert.Equal(t, ConsumerClosed, err)
+}
+
+func TestConsumer_directSubscription(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+
+	_, err := listener.Subscribe(1, offset)
+	require.NoError(t, err)
+
+	c := listener.NewConsumer(make(chan *EventMessage, 1))
+	_, err = c.Subscribe(1, offset)
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+
+	// The listener's own subscription is left alone.
+	assert.Equal(t, 1, server.count(methodSubscribe))
+	assert.Equal(t, 0, server.count(methodUnsubscribe))
+}
@@ -0,0 +1,304 @@
+package eventlistener
+
+import (
+	"bufio"
+	"container/list"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const dedupeStoreSize = 10000
+
+// DedupeKey identifies an event for deduplication.
+type DedupeKey func(event *Event) string
+
+// OffsetKey identifies an event by its type and offset.
+func OffsetKey(event *Event) string {
+	return fmt.Sprintf("%s:%d", event.EventType.ToString(), event.Offset)
+}
+
+// RequestKey identifies an event by casino, game and request ID.
+func RequestKey(event *Event) string {
+	return fmt.Sprintf("%d:%d:%d", event.CasinoID, event.GameID, event.RequestID)
+}
+
+// DedupeStore remembers the keys of handled events.
+type DedupeStore interface {
+	Contains(key string) (bool, error)
+	Add(key string) error
+}
+
+// MemoryStore is an in-memory DedupeStore keeping the most recently added keys.
+type MemoryStore struct {
+	sync.Mutex
+	size  int
+	keys  map[string]*list.Element
+	order *list.List
+}
+
+// NewMemoryStore creates a store which forgets the least recently used keys above size,
+// 10000 keys if size isn't positive.
+func NewMemoryStore(size int) *MemoryStore {
+	if size <= 0 {
+		size = dedupeStoreSize
+	}
+
+	return &MemoryStore{
+		size:  size,
+		keys:  make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+func (s *MemoryStore) Contains(key string) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	element, ok := s.keys[key]
+	if ok {
+		s.order.MoveToFront(element)
+	}
+	return ok, nil
+}
+
+func (s *MemoryStore) Add(key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if element, ok := s.keys[key]; ok {
+		s.order.MoveToFront(element)
+		return nil
+	}
+
+	s.keys[key] = s.order.PushFront(key)
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.keys, oldest.Value.(string))
+	}
+	return nil
+}
+
+// Len returns the number of stored keys.
+func (s *MemoryStore) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.order.Len()
+}
+
+func (s *MemoryStore) list() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	keys := make([]string, 0, s.order.Len())
+	for element := s.order.Back(); element != nil; element = element.Prev() {
+		keys = append(keys, element.Value.(string))
+	}
+	return keys
+}
+
+// FileStore is a DedupeStore persisted in an append-only file, so handled events survive restarts.
+// It keeps up to size keys like MemoryStore and compacts the file when it grows twice as large.
+type FileStore struct {
+	sync.Mutex
+	path   string
+	file   *os.File
+	lines  int
+	memory *MemoryStore
+}
+
+// NewFileStore opens or creates the store file at path and loads the keys from it.
+// A size which isn't positive keeps the default of NewMemoryStore.
+func NewFileStore(path string, size int) (*FileStore, error) {
+	s := &FileStore{
+		path:   path,
+		memory: NewMemoryStore(size),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+
+	return s, nil
+}
+
+func (s *FileStore) load() error {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if key := scanner.Text(); key != "" {
+			s.lines++
+			_ = s.memory.Add(key)
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *FileStore) Contains(key string) (bool, error) {
+	return s.memory.Contains(key)
+}
+
+func (s *FileStore) Add(key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, err := fmt.Fprintln(s.file, key); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.lines++
+
+	if err := s.memory.Add(key); err != nil {
+		return err
+	}
+
+	if s.lines > 2*s.memory.size {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact rewrites the file with the keys kept in memory.
+func (s *FileStore) compact() error {
+	keys := s.memory.list()
+
+	tmp := s.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	for _, key := range keys {
+		if _, err := fmt.Fprintln(writer, key); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	_ = s.file.Close()
+	s.file, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	s.lines = len(keys)
+	return nil
+}
+
+func (s *FileStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.file.Close()
+}
+
+// Deduplicator lets a handler see each event once, although resubscription in Run
+// and offset-based replay may deliver an event again.
+type Deduplicator struct {
+	sync.Mutex
+	store DedupeStore
+	key   DedupeKey
+}
+
+func NewDeduplicator(store DedupeStore, key DedupeKey) *Deduplicator {
+	return &Deduplicator{
+		store: store,
+		key:   key,
+	}
+}
+
+// Handle calls handler unless the event has already been handled.
+// The event is remembered only if handler succeeds, so a failed event is handled again when redelivered.
+// Events are handled one at a time.
+func (d *Deduplicator) Handle(event *Event, handler func(*Event) error) error {
+	d.Lock()
+	defer d.Unlock()
+
+	key := d.key(event)
+	seen, err := d.store.Contains(key)
+	if err != nil || seen {
+		return err
+	}
+
+	if err := handler(event); err != nil {
+		return err
+	}
+
+	return d.store.Add(key)
+}
+
+// Filter removes the events which have already been handled. Unlike Handle it doesn't remember
+// the rest: call Commit once they are handled, so a failed message is delivered again.
+// Returns nil if all events have been handled.
+func (d *Deduplicator) Filter(message *EventMessage) (*EventMessage, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	events := make([]*Event, 0, len(message.Events))
+	keys := make(map[string]struct{}, len(message.Events))
+	for _, event := range message.Events {
+		key := d.key(event)
+		if _, ok := keys[key]; ok {
+			continue
+		}
+		seen, err := d.store.Contains(key)
+		if err != nil {
+			return nil, err
+		}
+		if seen {
+			continue
+		}
+		keys[key] = struct{}{}
+		events = append(events, event)
+	}
+
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	return &EventMessage{Offset: events[len(events)-1].Offset, Events: events}, nil
+}
+
+// Commit remembers the events of a message returned by Filter after they have been handled.
+func (d *Deduplicator) Commit(message *EventMessage) error {
+	d.Lock()
+	defer d.Unlock()
+
+	for _, event := range message.Events {
+		if err := d.store.Add(d.key(event)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package eventlistener
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+func TestOffsetKey(t *testing.T) {
+	assert.Equal(t, "event_1:5", OffsetKey(&Event{EventType: 1, Offset: 5}))
+	assert.Equal(t, "1:2:3", RequestKey(&Event{CasinoID: 1, GameID: 2, RequestID: 3}))
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(2)
+
+	require.NoError(t, store.Add("a"))
+	require.NoError(t, store.Add("b"))
+
+	// "a" becomes the most recently used
+	ok, err := store.Contains("a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, store.Add("c"))
+	assert.Equal(t, 2, store.Len())
+
+	ok, _ = store.Contains("b")
+	assert.False(t, ok)
+	ok, _ = store.Contains("a")
+	assert.True(t, ok)
+
+	// A size which isn't positive keeps the default instead of forgetting every key
+	for _, size := range []int{0, -1} {
+		store = NewMemoryStore(size)
+		require.NoError(t, store.Add("a"))
+		ok, _ = store.Contains("a")
+		assert.True(t, ok)
+		assert.Equal(t, dedupeStoreSize, store.size)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedupe")
+
+	store, err := NewFileStore(path, 2)
+	require.NoError(t, err)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, store.Add(key))
+	}
+	require.NoError(t, store.Close())
+
+	store, err = NewFileStore(path, 2)
+	require.NoError(t, err)
+	defer store.Close()
+
+	ok, _ := store.Contains("e")
+	assert.True(t, ok)
+	ok, _ = store.Contains("d")
+	assert.True(t, ok)
+	ok, _ = store.Contains("a")
+	assert.False(t, ok)
+	assert.LessOrEqual(t, store.lines, 4)
+}
+
+func TestDeduplicator_Handle(t *testing.T) {
+	deduplicator := NewDeduplicator(NewMemoryStore(10), OffsetKey)
+	event := &Event{EventType: 1, Offset: 1}
+
+	calls := 0
+	handler := func(*Event) error {
+		calls++
+		if calls == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	}
+
+	require.Error(t, deduplicator.Handle(event, handler))
+	require.NoError(t, deduplicator.Handle(event, handler))
+	require.NoError(t, deduplicator.Handle(event, handler))
+	assert.Equal(t, 2, calls)
+}
+
+func TestDeduplicator_Filter(t *testing.T) {
+	deduplicator := NewDeduplicator(NewMemoryStore(10), OffsetKey)
+
+	message := &EventMessage{Offset: 2, Events: []*Event{{EventType: 1, Offset: 1}, {EventType: 1, Offset: 2}, {EventType: 1, Offset: 2}}}
+	filtered, err := deduplicator.Filter(message)
+	require.NoError(t, err)
+	assert.Len(t, filtered.Events, 2)
+
+	// Not committed, e.g. the handler failed: the events are delivered again
+	filtered, err = deduplicator.Filter(message)
+	require.NoError(t, err)
+	require.Len(t, filtered.Events, 2)
+	require.NoError(t, deduplicator.Commit(filtered))
+
+	message = &EventMessage{Offset: 3, Events: []*Event{{EventType: 1, Offset: 2}, {EventType: 1, Offset: 3}}}
+	filtered, err = deduplicator.Filter(message)
+	require.NoError(t, err)
+	require.Len(t, filtered.Events, 1)
+	assert.Equal(t, uint64(3), filtered.Offset)
+	require.NoError(t, deduplicator.Commit(filtered))
+
+	filtered, err = deduplicator.Filter(message)
+	require.NoError(t, err)
+	assert.Nil(t, filtered)
+}
@@ -3,6 +3,10 @@ package eventlistener
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type EventType int
@@ -15,13 +19,63 @@ type Event struct {
 	RequestID uint64          `json:"req_id"`
 	EventType EventType       `json:"event_type"`
 	Data      json.RawMessage `json:"data"`
+
+	ReceivedAt time.Time `json:"-"` // When the frame was read by the listener.
+	Timestamp  time.Time `json:"-"` // Server time, if the listener extracts it, see EventListener.Timestamp.
 }
 
 type EventMessage struct {
 	Offset uint64   `json:"offset"` // last event.offset
 	Events []*Event `json:"events"`
+
+	ReceivedAt time.Time `json:"-"` // When the frame was read by the listener.
+
+	pooled bool
+}
+
+var eventMessagePool = sync.Pool{
+	New: func() interface{} { return new(EventMessage) },
+}
+
+func acquireEventMessage() *EventMessage {
+	message := eventMessagePool.Get().(*EventMessage)
+	message.pooled = false
+	return message
+}
+
+// Release returns the message and its events to the pool, they must not be used afterwards.
+// Only messages received on the listener channel while it has no consumers and sinks are pooled,
+// the events of other messages may be shared, for them Release does nothing.
+func (m *EventMessage) Release() {
+	if !m.pooled {
+		return
+	}
+
+	// Drop the events filtered out of the slice, the decoder would reuse them twice
+	all := m.Events[:cap(m.Events)]
+	for i := len(m.Events); i < len(all); i++ {
+		all[i] = nil
+	}
+	for _, event := range m.Events {
+		*event = Event{Data: event.Data[:0]}
+	}
+
+	*m = EventMessage{Events: m.Events[:0]}
+	eventMessagePool.Put(m)
 }
 
 func (e EventType) ToString() string {
 	return fmt.Sprintf("event_%d", e)
 }
+
+// ParseEventType returns the event type of a topic, e.g. "event_1".
+func ParseEventType(topic string) (EventType, error) {
+	if !strings.HasPrefix(topic, "event_") {
+		return 0, fmt.Errorf("invalid topic %q", topic)
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(topic, "event_"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid topic %q", topic)
+	}
+	return EventType(n), nil
+}
@@ -0,0 +1,108 @@
+// Package grpcbridge serves the events of an EventListener over gRPC, so only one process
+// per cluster holds the websocket connection to the action monitor and fans events out internally.
+package grpcbridge
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative eventstream.proto
+
+import (
+	"context"
+	"errors"
+	"github.com/DaoCasino/platform-action-monitor-client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the EventStream service on top of an EventListener.
+// It streams whatever the listener is subscribed to, filtered per client.
+type Server struct {
+	UnimplementedEventStreamServer
+
+	broadcaster *eventlistener.Broadcaster
+}
+
+// NewServer creates a server keeping up to size recent events to resume streams and attaches it to the listener.
+func NewServer(listener *eventlistener.EventListener, size int) *Server {
+	return &Server{
+		broadcaster: eventlistener.NewBroadcaster(listener, size),
+	}
+}
+
+func (s *Server) Subscribe(request *SubscribeRequest, stream EventStream_SubscribeServer) error {
+	ctx := stream.Context()
+
+	filter := &eventlistener.EventFilter{
+		EventTypes: make(map[eventlistener.EventType]struct{}),
+		CasinoIDs:  make(map[uint64]struct{}),
+	}
+	for _, eventType := range request.EventTypes {
+		filter.EventTypes[eventlistener.EventType(eventType)] = struct{}{}
+	}
+	for _, casinoID := range request.CasinoIds {
+		filter.CasinoIDs[casinoID] = struct{}{}
+	}
+
+	cursor := make(eventlistener.Cursor)
+	for eventType, offset := range request.Offsets {
+		cursor[eventlistener.EventType(eventType)] = offset
+	}
+
+	events, err := s.history(ctx, cursor, filter, stream)
+	if err != nil {
+		return err
+	}
+
+	for {
+		next, err := events.Next(ctx)
+		if err == eventlistener.ListenerClosed {
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		if err != nil {
+			return status.FromContextError(err).Err()
+		}
+
+		for _, event := range next {
+			if err := stream.Send(toProto(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// history sends the events since the cursor and returns the stream which continues after them.
+// History is read in pages of at most Broadcaster.HistoryLimit events, each one sent before the next is read.
+func (s *Server) history(ctx context.Context, cursor eventlistener.Cursor, filter *eventlistener.EventFilter,
+	stream EventStream_SubscribeServer) (*eventlistener.Stream, error) {
+	for {
+		events, history, err := s.broadcaster.Open(ctx, cursor, filter)
+		if errors.Is(err, eventlistener.ErrNotSubscribed) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "read history: %v", err)
+		}
+		history = append(history, events.Buffered()...)
+
+		for _, event := range history {
+			if err := stream.Send(toProto(event)); err != nil {
+				return nil, err
+			}
+		}
+
+		if !events.Truncated() {
+			return events, nil
+		}
+		cursor = events.Cursor()
+	}
+}
+
+func toProto(event *eventlistener.Event) *Event {
+	return &Event{
+		Offset:    event.Offset,
+		Sender:    event.Sender,
+		CasinoId:  event.CasinoID,
+		GameId:    event.GameID,
+		ReqId:     event.RequestID,
+		EventType: int32(event.EventType),
+		Data:      event.Data,
+	}
+}
@@ -0,0 +1,168 @@
+package grpcbridge
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/DaoCasino/platform-action-monitor-client"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, server *Server) EventStreamClient {
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	RegisterEventStreamServer(grpcServer, server)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return NewEventStreamClient(conn)
+}
+
+// newTestMonitor serves an action monitor with count events of every topic and accepts any request.
+// It returns a listener connected to it, closed by the caller.
+func newTestMonitor(t *testing.T, count uint64) *eventlistener.EventListener {
+	upgrader := websocket.Upgrader{}
+	monitor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var request struct {
+				ID     string `json:"id"`
+				Method string `json:"method"`
+				Params struct {
+					Topic  string   `json:"topic"`
+					Topics []string `json:"topics"`
+					Offset uint64   `json:"offset"`
+				} `json:"params"`
+			}
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+
+			switch request.Method {
+			case "getHeadOffsets":
+				heads := make(map[string]uint64)
+				for _, topic := range request.Params.Topics {
+					heads[topic] = count
+				}
+				_ = conn.WriteJSON(map[string]interface{}{"id": request.ID, "result": heads})
+			case "subscribe":
+				_ = conn.WriteJSON(map[string]interface{}{"id": request.ID, "result": true})
+				eventType, _ := eventlistener.ParseEventType(request.Params.Topic)
+				for offset := request.Params.Offset; offset < count; offset++ {
+					event := &eventlistener.Event{Offset: offset, EventType: eventType, Data: json.RawMessage(`{}`)}
+					message := &eventlistener.EventMessage{Offset: offset, Events: []*eventlistener.Event{event}}
+					_ = conn.WriteJSON(map[string]interface{}{"result": message})
+				}
+			default:
+				_ = conn.WriteJSON(map[string]interface{}{"id": request.ID, "result": true})
+			}
+		}
+	}))
+	t.Cleanup(monitor.Close)
+
+	listener := eventlistener.New(strings.TrimPrefix(monitor.URL, "http://"), eventlistener.WithRangeIdleWait(time.Second))
+	require.NoError(t, listener.ListenAndServe(context.Background()))
+	return listener
+}
+
+func publish(t *testing.T, server *Server, events ...*eventlistener.Event) {
+	message := &eventlistener.EventMessage{Offset: events[len(events)-1].Offset, Events: events}
+	require.NoError(t, server.broadcaster.Write(context.Background(), message))
+}
+
+func TestServer_Subscribe(t *testing.T) {
+	listener := newTestMonitor(t, 0)
+	server := NewServer(listener, 10)
+	client := newTestClient(t, server)
+
+	ok, err := listener.BatchSubscribe([]eventlistener.EventType{1, 2}, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	publish(t, server,
+		&eventlistener.Event{Offset: 0, EventType: 1, CasinoID: 1},
+		&eventlistener.Event{Offset: 1, EventType: 1, CasinoID: 2},
+		&eventlistener.Event{Offset: 0, EventType: 2, CasinoID: 1},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Resumes from the buffer after offset 0 of event_1
+	stream, err := client.Subscribe(ctx, &SubscribeRequest{
+		EventTypes: []int32{1},
+		CasinoIds:  []uint64{2},
+		Offsets:    map[int32]uint64{1: 0},
+	})
+	require.NoError(t, err)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), event.Offset)
+	assert.Equal(t, uint64(2), event.CasinoId)
+
+	publish(t, server, &eventlistener.Event{Offset: 2, EventType: 1, CasinoID: 2, Data: json.RawMessage(`{"a":1}`)})
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), event.Offset)
+	assert.JSONEq(t, `{"a":1}`, string(event.Data))
+
+	listener.Close()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestServer_SubscribeHistory(t *testing.T) {
+	listener := newTestMonitor(t, 5)
+	defer listener.Close()
+	server := NewServer(listener, 10)
+	server.broadcaster.HistoryLimit = 2
+	client := newTestClient(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Not subscribed, the bridge doesn't read the history of event_7 for the client
+	stream, err := client.Subscribe(ctx, &SubscribeRequest{Offsets: map[int32]uint64{7: 0}})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Subscribed from the head, the history is read in pages
+	ok, err := listener.Subscribe(1, 5)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	stream, err = client.Subscribe(ctx, &SubscribeRequest{Offsets: map[int32]uint64{1: 0}})
+	require.NoError(t, err)
+	for offset := uint64(1); offset < 5; offset++ {
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, offset, event.Offset)
+	}
+}
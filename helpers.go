@@ -19,12 +19,12 @@ func closeMessage(conn *websocket.Conn, writeWait time.Duration) error {
 	return conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 }
 
-func writeMessage(conn *websocket.Conn, writeWait time.Duration, message []byte) error {
+func writeMessage(conn *websocket.Conn, writeWait time.Duration, frameType int, message []byte) error {
 	if err := conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
 		return err
 	}
 
-	w, err := conn.NextWriter(websocket.TextMessage)
+	w, err := conn.NextWriter(frameType)
 	if err != nil {
 		return err
 	}
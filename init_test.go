@@ -1,11 +1,16 @@
 package eventlistener
 
-import "flag"
+import (
+	"flag"
+	"os"
+	"testing"
+)
 
-var token = flag.String("token", "83e81fbcb975e4f3ce8ddf28a99e01e738154be3ff09308a89b52cbd289594a9", "user token")
+var token = flag.String("token", os.Getenv("ACTION_MONITOR_TOKEN"), "user token, ACTION_MONITOR_TOKEN by default")
 var addr = flag.String("addr", ":8888", "action monitor service address")
 
-func init() {
+func TestMain(m *testing.M) {
 	flag.Parse()
 	EnableDebugLogging()
+	os.Exit(m.Run())
 }
@@ -0,0 +1,89 @@
+package eventlistener
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+)
+
+const jsonrpcVersion = "2.0"
+
+// ErrInvalidResponse is returned in strict mode for a response which isn't valid JSON-RPC 2.0.
+var ErrInvalidResponse = errors.New("invalid JSON-RPC 2.0 response")
+
+// strictRequestMessage is a JSON-RPC 2.0 request, params are omitted rather than null.
+type strictRequestMessage struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      string      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// UnmarshalJSON decodes an error object, noting the missing members instead of failing on them.
+func (e *RPCError) UnmarshalJSON(data []byte) error {
+	object := struct {
+		Code    *int            `json:"code"`
+		Message *string         `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+
+	*e = RPCError{Data: object.Data}
+	if object.Code != nil {
+		e.Code = *object.Code
+	} else {
+		e.invalid = "code"
+	}
+	if object.Message != nil {
+		e.Message = *object.Message
+	} else {
+		e.invalid = "message"
+	}
+	return nil
+}
+
+// decodeRPCError decodes a JSON error object, nil if it is absent or null.
+func decodeRPCError(data []byte) (*RPCError, error) {
+	if !isSet(data) {
+		return nil, nil
+	}
+
+	rpcError := new(RPCError)
+	if err := json.Unmarshal(data, rpcError); err != nil {
+		return nil, err
+	}
+	return rpcError, nil
+}
+
+// validate checks a response or a notification against JSON-RPC 2.0.
+func (r *responseMessage) validate() error {
+	if r.JSONRPC != jsonrpcVersion {
+		return fmt.Errorf("%w: jsonrpc %q", ErrInvalidResponse, r.JSONRPC)
+	}
+	if r.ID != nil && (r.Result != nil) == (r.Error != nil) {
+		return fmt.Errorf("%w: either result or error expected", ErrInvalidResponse)
+	}
+	if r.Error != nil && r.Error.invalid != "" {
+		return fmt.Errorf("%w: error without %s", ErrInvalidResponse, r.Error.invalid)
+	}
+	return nil
+}
+
+// splitBatch returns the frames of a batch, a JSON array of responses, or false if the message isn't a batch.
+func splitBatch(codec Codec, message []byte) ([]json.RawMessage, bool, error) {
+	if codec.FrameType() != websocket.TextMessage {
+		return nil, false, nil
+	}
+	if i := skipSpace(message, 0); i >= len(message) || message[i] != '[' {
+		return nil, false, nil
+	}
+
+	var frames []json.RawMessage
+	if err := json.Unmarshal(message, &frames); err != nil {
+		return nil, true, err
+	}
+	return frames, true, nil
+}
@@ -0,0 +1,143 @@
+package eventlistener
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRequestMessage_encodeStrict(t *testing.T) {
+	request := &requestMessage{ID: "1", Method: "listTopics"}
+
+	message, err := request.encode(JSONCodec, false)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"1","method":"listTopics","params":null}`, string(message))
+
+	message, err = request.encode(JSONCodec, true)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","method":"listTopics"}`, string(message))
+
+	request.Params = map[string]string{"topic": "event_1"}
+	message, err = request.encode(MsgpackCodec, true)
+	require.NoError(t, err)
+	decoded, err := transcode(MsgpackCodec, message)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"1","method":"listTopics","params":{"topic":"event_1"}}`, string(decoded))
+}
+
+func TestResponseMessage_validate(t *testing.T) {
+	for frame, valid := range map[string]bool{
+		`{"jsonrpc":"2.0","id":"1","result":true}`:                                          true,
+		`{"jsonrpc":"2.0","id":"1","result":null}`:                                          true,
+		`{"jsonrpc":"2.0","id":"1","error":{"code":-1,"message":"x"}}`:                      true,
+		`{"jsonrpc":"2.0","id":"1","error":{"code":-1,"message":"x","data":[1]}}`:           true,
+		`{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`:       true,
+		`{"jsonrpc":"2.0","method":"tokenExpired"}`:                                         true,
+		`{"id":"1","result":true}`:                                                          false,
+		`{"jsonrpc":"1.0","id":"1","result":true}`:                                          false,
+		`{"jsonrpc":"2.0","id":"1"}`:                                                        false,
+		`{"jsonrpc":"2.0","id":"1","result":true,"error":{"code":-1,"message":"x"}}`:        false,
+		`{"jsonrpc":"2.0","id":"1","error":{"message":"x"}}`:                                false,
+		`{"jsonrpc":"2.0","id":"1","error":{"code":-1}}`:                                    false,
+		`{"jsonrpc":"2.0","method":"tokenExpired","error":{"code":-1,"data":{"at":"now"}}}`: false,
+	} {
+		response, _, err := decodeJSONFrame([]byte(frame))
+		require.NoError(t, err, frame)
+		err = response.validate()
+		if valid {
+			assert.NoError(t, err, frame)
+		} else {
+			assert.True(t, errors.Is(err, ErrInvalidResponse), frame)
+		}
+	}
+
+	for _, frame := range []string{
+		`{"jsonrpc":"2.0","id":"1","error":{"code":"x","message":"x"}}`,
+		`{"jsonrpc":"2.0","id":"1","error":{"code":1.5,"message":"x"}}`,
+		`{"jsonrpc":"2.0","id":"1","error":{"code":-1,"message":1}}`,
+		`{"jsonrpc":"2.0","id":"1","error":"x"}`,
+	} {
+		_, _, err := decodeJSONFrame([]byte(frame))
+		assert.Error(t, err, frame)
+	}
+}
+
+func TestRPCError_data(t *testing.T) {
+	frame := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      "1",
+		"error":   map[string]interface{}{"code": -32000, "message": "rate limited", "data": map[string]int{"retry_after": 5}},
+	}
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec, CBORCodec, textCodec{}} {
+		message, err := encodeFrame(codec, frame)
+		require.NoError(t, err)
+
+		response, _, err := decodeFrame(codec, message, nil)
+		require.NoError(t, err)
+		require.NoError(t, response.validate())
+		assert.Equal(t, -32000, response.Error.Code)
+		assert.Equal(t, "rate limited", response.Error.Message)
+		assert.JSONEq(t, `{"retry_after":5}`, string(response.Error.Data))
+	}
+}
+
+func TestEventListener_processBatch(t *testing.T) {
+	listener := NewEventListener("", nil)
+	listener.Logger = NopLogger()
+	batch := []byte(` [{"jsonrpc":"2.0","id":"1","result":true},
+		{"jsonrpc":"2.0","id":"2","error":{"code":-1,"message":"x","data":"y"}},
+		{"result":{"offset":0,"events":[{"offset":0,"event_type":1}]}},
+		{"jsonrpc":"2.0","id":"3"}]`)
+
+	// Not a frame unless in strict mode
+	_, err := listener.processMessage(JSONCodec, batch)
+	assert.Error(t, err)
+
+	listener.StrictJSONRPC = true
+	responses, err := listener.processMessage(JSONCodec, batch)
+	require.NoError(t, err)
+	require.Len(t, responses, 3)
+	assert.Equal(t, "1", *responses[0].ID)
+	assert.NoError(t, responses[0].err)
+	assert.Equal(t, &RPCError{Code: -1, Message: "x", Data: []byte(`"y"`)}, responses[1].Error)
+	assert.True(t, errors.Is(responses[2].err, ErrInvalidResponse))
+
+	_, err = listener.processMessage(JSONCodec, []byte(`[{"jsonrpc":"2.0","id":"1","result":true},`))
+	assert.Error(t, err)
+}
+
+func TestEventListener_strictJSONRPC(t *testing.T) {
+	server := newTestServer(t)
+	server.publish(1, `{"x":1}`)
+
+	parentContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan *EventMessage, 10)
+	listener := New(server.addr(), WithEvents(events), WithStrictJSONRPC())
+	require.NoError(t, listener.ListenAndServe(parentContext))
+
+	// The responses of a lenient server are rejected, the connection goes on
+	var topics []string
+	err := listener.Call(context.Background(), methodListTopics, nil, &topics)
+	assert.True(t, errors.Is(err, ErrInvalidResponse), err)
+
+	server.Lock()
+	server.strict = true
+	server.Unlock()
+
+	require.NoError(t, listener.Call(context.Background(), methodListTopics, nil, &topics))
+	assert.Equal(t, []string{"event_1"}, topics)
+
+	ok, err := listener.Subscribe(1, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.JSONEq(t, `{"x":1}`, string(receiveEvent(t, events).Data))
+
+	_, err = listener.Unsubscribe(2)
+	rpcError := new(RPCError)
+	require.True(t, errors.As(err, &rpcError))
+	assert.Equal(t, "topic not subscribed", rpcError.Message)
+}
@@ -0,0 +1,261 @@
+package eventlistener
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TimestampFunc extracts the server time of an event, returns false if the event has none.
+type TimestampFunc func(event *Event) (time.Time, bool)
+
+// eosioTimeLayout is the layout of block times, UTC without zone.
+const eosioTimeLayout = "2006-01-02T15:04:05.999999999"
+
+// DataTimestamp extracts the server time from a top-level field of Event.Data.
+// The field may be an RFC 3339 or block time string, or unix time in seconds or milliseconds.
+func DataTimestamp(field string) TimestampFunc {
+	return func(event *Event) (time.Time, bool) {
+		var data map[string]json.RawMessage
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return time.Time{}, false
+		}
+
+		raw, ok := data[field]
+		if !ok {
+			return time.Time{}, false
+		}
+
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t, true
+			}
+			if t, err := time.ParseInLocation(eosioTimeLayout, s, time.UTC); err == nil {
+				return t, true
+			}
+			return time.Time{}, false
+		}
+
+		n, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		if n > 1e12 {
+			return time.Unix(0, int64(n*float64(time.Millisecond))), true
+		}
+		return time.Unix(0, int64(n*float64(time.Second))), true
+	}
+}
+
+// Lag is how far the committed events of an event type are behind.
+type Lag struct {
+	Head      uint64        // Last offset received from the server or reported by HeadOffsets.
+	Committed uint64        // Last offset delivered.
+	Offsets   uint64        // Head minus committed offset.
+	Time      time.Duration // Age of the last committed event while behind the head, 0 once caught up.
+	Latency   time.Duration // Receive time minus server time of the last committed event, 0 without server time.
+}
+
+type lagState struct {
+	head       uint64
+	committed  uint64
+	hasCommit  bool
+	receivedAt time.Time
+	timestamp  time.Time
+}
+
+// LagTracker computes the offset and time lag per event type from the observed and the committed events.
+// The listener tracks delivery to its channel, consumers and sinks, see EventListener.Lags.
+// Use a tracker of your own to commit events once they are processed.
+type LagTracker struct {
+	sync.Mutex
+	states map[EventType]*lagState
+	now    func() time.Time
+}
+
+func NewLagTracker() *LagTracker {
+	return &LagTracker{
+		states: make(map[EventType]*lagState),
+		now:    time.Now,
+	}
+}
+
+func (t *LagTracker) state(eventType EventType) *lagState {
+	state, ok := t.states[eventType]
+	if !ok {
+		state = new(lagState)
+		t.states[eventType] = state
+	}
+	return state
+}
+
+// Observe moves the heads of the event types of the message.
+func (t *LagTracker) Observe(message *EventMessage) {
+	t.Lock()
+	defer t.Unlock()
+
+	// Offsets are per event type, the message offset is the head only of a single type message
+	single := true
+	for _, event := range message.Events {
+		if event.EventType != message.Events[0].EventType {
+			single = false
+			break
+		}
+	}
+
+	for _, event := range message.Events {
+		head := event.Offset
+		if single {
+			head = maxOffset(message.Offset, event.Offset)
+		}
+
+		state := t.state(event.EventType)
+		if head > state.head {
+			state.head = head
+		}
+	}
+}
+
+// ObserveHead moves the head of the event type to the last event of the server, given its head offset,
+// the offset the next event will get as returned by EventListener.HeadOffsets. The offset lag then
+// counts the events not received yet, not only the received ones which are not committed.
+func (t *LagTracker) ObserveHead(eventType EventType, offset uint64) {
+	if offset == 0 {
+		return // no events yet
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	state := t.state(eventType)
+	if offset-1 > state.head {
+		state.head = offset - 1
+	}
+}
+
+// Commit marks the event as processed.
+func (t *LagTracker) Commit(event *Event) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.commit(event.EventType, event.Offset, event.ReceivedAt, event.Timestamp)
+}
+
+func (t *LagTracker) commit(eventType EventType, offset uint64, receivedAt, timestamp time.Time) {
+	state := t.state(eventType)
+	if state.hasCommit && offset < state.committed {
+		return
+	}
+
+	state.committed = offset
+	state.hasCommit = true
+	state.receivedAt = receivedAt
+	state.timestamp = timestamp
+	if offset > state.head {
+		state.head = offset
+	}
+}
+
+// Lag returns the lag of the event type, false if no events of the type were observed.
+func (t *LagTracker) Lag(eventType EventType) (Lag, bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	state, ok := t.states[eventType]
+	if !ok {
+		return Lag{}, false
+	}
+	return t.lag(state), true
+}
+
+// Lags returns the lag of every observed event type.
+func (t *LagTracker) Lags() map[EventType]Lag {
+	t.Lock()
+	defer t.Unlock()
+
+	lags := make(map[EventType]Lag, len(t.states))
+	for eventType, state := range t.states {
+		lags[eventType] = t.lag(state)
+	}
+	return lags
+}
+
+func (t *LagTracker) lag(state *lagState) Lag {
+	lag := Lag{Head: state.head, Committed: state.committed}
+	if !state.hasCommit {
+		// Nothing delivered yet, everything up to the head is behind
+		lag.Offsets = state.head + 1
+		return lag
+	}
+
+	lag.Offsets = state.head - state.committed
+
+	eventTime := state.timestamp
+	if eventTime.IsZero() {
+		eventTime = state.receivedAt
+	}
+	if lag.Offsets > 0 && !eventTime.IsZero() {
+		lag.Time = t.now().Sub(eventTime)
+	}
+	if !state.timestamp.IsZero() && !state.receivedAt.IsZero() {
+		lag.Latency = state.receivedAt.Sub(state.timestamp)
+	}
+	return lag
+}
+
+// lagMark is the last event of a type in a message, committed once the message is delivered.
+type lagMark struct {
+	eventType  EventType
+	offset     uint64
+	receivedAt time.Time
+	timestamp  time.Time
+}
+
+// lagMarks collects the last event of each type, the message may be released as soon as it is delivered.
+func lagMarks(message *EventMessage) []lagMark {
+	var marks []lagMark
+	for _, event := range message.Events {
+		mark := lagMark{event.EventType, event.Offset, event.ReceivedAt, event.Timestamp}
+
+		found := false
+		for i := range marks {
+			if marks[i].eventType == event.EventType {
+				marks[i] = mark
+				found = true
+				break
+			}
+		}
+		if !found {
+			marks = append(marks, mark)
+		}
+	}
+	return marks
+}
+
+func (t *LagTracker) commitMarks(marks []lagMark) {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, mark := range marks {
+		t.commit(mark.eventType, mark.offset, mark.receivedAt, mark.timestamp)
+	}
+}
+
+func maxOffset(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Lag returns the lag of the event type behind the server, false if no events of the type were received.
+func (e *EventListener) Lag(eventType EventType) (Lag, bool) {
+	return e.lag.Lag(eventType)
+}
+
+// Lags returns the lag of every received event type.
+func (e *EventListener) Lags() map[EventType]Lag {
+	return e.lag.Lags()
+}
@@ -0,0 +1,109 @@
+package eventlistener
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDataTimestamp(t *testing.T) {
+	want := time.Date(2020, 5, 1, 12, 0, 0, 500000000, time.UTC)
+	extract := DataTimestamp("ts")
+
+	for _, data := range []string{
+		`{"ts":"2020-05-01T12:00:00.5Z"}`,
+		`{"ts":"2020-05-01T14:00:00.5+02:00"}`,
+		`{"ts":"2020-05-01T12:00:00.500"}`,
+		`{"ts":1588334400.5}`,
+		`{"ts":1588334400500}`,
+	} {
+		ts, ok := extract(&Event{Data: json.RawMessage(data)})
+		require.True(t, ok, data)
+		assert.True(t, want.Equal(ts), "%s: %s", data, ts)
+	}
+
+	for _, data := range []string{`{}`, `{"ts":"yesterday"}`, `{"ts":true}`, `[]`, ``} {
+		_, ok := extract(&Event{Data: json.RawMessage(data)})
+		assert.False(t, ok, data)
+	}
+}
+
+func TestLagTracker(t *testing.T) {
+	now := time.Now()
+	tracker := NewLagTracker()
+	tracker.now = func() time.Time { return now }
+
+	_, ok := tracker.Lag(1)
+	assert.False(t, ok)
+
+	events := []*Event{
+		{EventType: 1, Offset: 8, ReceivedAt: now.Add(-3 * time.Second), Timestamp: now.Add(-5 * time.Second)},
+		{EventType: 1, Offset: 9},
+	}
+	tracker.Observe(&EventMessage{Offset: 10, Events: events})
+
+	lag, ok := tracker.Lag(1)
+	require.True(t, ok)
+	assert.Equal(t, Lag{Head: 10, Offsets: 11}, lag)
+
+	tracker.Commit(events[0])
+	lag, _ = tracker.Lag(1)
+	assert.Equal(t, Lag{Head: 10, Committed: 8, Offsets: 2, Time: 5 * time.Second, Latency: 2 * time.Second}, lag)
+
+	tracker.Commit(&Event{EventType: 1, Offset: 10, ReceivedAt: now.Add(-time.Second)})
+	tracker.Commit(events[1]) // older commits are ignored
+	lag, _ = tracker.Lag(1)
+	assert.Equal(t, Lag{Head: 10, Committed: 10}, lag)
+
+	// Offsets of mixed messages are per event type
+	tracker.Observe(&EventMessage{Offset: 20, Events: []*Event{{EventType: 2, Offset: 3}, {EventType: 1, Offset: 20}}})
+	tracker.Commit(&Event{EventType: 2, Offset: 1, ReceivedAt: now.Add(-time.Second)})
+	assert.Equal(t, map[EventType]Lag{
+		1: {Head: 20, Committed: 10, Offsets: 10, Time: time.Second},
+		2: {Head: 3, Committed: 1, Offsets: 2, Time: time.Second},
+	}, tracker.Lags())
+
+	// Server heads count the events not received yet, older heads are ignored
+	tracker.ObserveHead(1, 26)
+	tracker.ObserveHead(2, 2)
+	tracker.ObserveHead(3, 0)
+	lags := tracker.Lags()
+	assert.Equal(t, Lag{Head: 25, Committed: 10, Offsets: 15, Time: time.Second}, lags[1])
+	assert.Equal(t, uint64(3), lags[2].Head)
+	assert.NotContains(t, lags, EventType(3))
+}
+
+func TestEventListener_Lags(t *testing.T) {
+	server := newTestServer(t)
+	server.publish(1, `{"ts":"2020-05-01T12:00:00Z"}`)
+
+	events := make(chan *EventMessage)
+	listener := newTestListener(t, server, events)
+	listener.Timestamp = DataTimestamp("ts")
+
+	ok, err := listener.Subscribe(1, offset)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	event := receiveEvent(t, events)
+	assert.WithinDuration(t, time.Now(), event.ReceivedAt, time.Minute)
+	assert.Equal(t, time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC), event.Timestamp.UTC())
+
+	assert.Eventually(t, func() bool {
+		lag, ok := listener.Lag(1)
+		return ok && lag.Offsets == 0 && lag.Latency > 0
+	}, waitEventsTimeout, 10*time.Millisecond)
+	assert.Contains(t, listener.Metrics().Lags, EventType(1))
+
+	// Events not received yet are behind once the server heads are queried
+	server.publish(2, `{}`)
+	server.publish(2, `{}`)
+	_, err = listener.HeadOffsets(context.Background(), 2)
+	require.NoError(t, err)
+	lag, ok := listener.Lag(2)
+	require.True(t, ok)
+	assert.Equal(t, Lag{Head: 1, Offsets: 2}, lag)
+}
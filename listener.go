@@ -1,10 +1,12 @@
 package eventlistener
 
 import (
+	"compress/flate"
 	"context"
-	"encoding/json"
 	"errors"
 	"github.com/gorilla/websocket"
+	"github.com/lucsky/cuid"
+	"net"
 	"net/url"
 	"sync"
 	"time"
@@ -15,14 +17,23 @@ const (
 	pongWait             = 60 * time.Second
 	responseWait         = 10 * time.Second
 	pingPeriod           = (pongWait * 9) / 10
-	messageSizeLimit     = 0
+	messageSizeLimit     = 4 << 20
 	reconnectionAttempts = 5
 	reconnectionDelay    = 2 * time.Second
+	rangeIdleWait        = 2 * time.Second
+	compressionLevel     = flate.BestSpeed
+	recoveryBatchSize    = 64
+	maxInFlight          = 64
 )
 
-var ListenerClosed = errors.New("listener closed")
+var (
+	ListenerClosed     = errors.New("listener closed")
+	ConnectionClosed   = errors.New("connection closed before response")
+	ErrMessageTooLarge = errors.New("message too large")
+)
 
 type EventListener struct {
+	Name             string        // Identity of the listener in logs, a generated ID by default.
 	Addr             string        // TCP address to listen.
 	Token            string        // User token
 	MessageSizeLimit int64         // Maximum message size allowed from client.
@@ -30,24 +41,65 @@ type EventListener struct {
 	PongWait         time.Duration // Time allowed to read the next pong message from the peer.
 	PingPeriod       time.Duration // Send pings to peer with this period. Must be less than pongWait.
 	ResponseWait     time.Duration // Time allowed to wait response from server.
+	MaxInFlight      int           // Requests written without waiting for their responses, further ones wait for a slot.
+	RangeIdleWait    time.Duration // Time without events after which ReadRange considers the head reached.
 
 	ReconnectionDelay    time.Duration // Delay between connection attempts, used in RunListener
 	ReconnectionAttempts int           // used in RunListener
 
-	conn  *websocket.Conn
-	event chan<- *EventMessage
+	Logger     Logger   // Defaults to the logger set by SetDebugLogger. Set Logger, Name and Addr before starting.
+	RedactKeys []string // Keys redacted in logged frames besides the token, e.g. secrets inside Event.Data. Set before starting.
+
+	Timestamp TimestampFunc // Extracts the server time of events for the time lag, e.g. DataTimestamp("timestamp").
+
+	BatchSize        int  // Hint for the maximum number of events per frame sent on subscribe, 0 leaves it to the server.
+	OversizeRecovery bool // On ErrMessageTooLarge Run reconnects with the batch size hint halved instead of stopping.
+
+	Codec Codec // Wire format negotiated as websocket subprotocol, JSON if not set or not supported by the server.
+
+	EnableCompression bool // Negotiate permessage-deflate, used only if the server supports it.
+	CompressionLevel  int  // Level of compression of sent messages, see compress/flate.
+
+	StrictJSONRPC bool // Send JSON-RPC 2.0 requests, accept batched responses and reject invalid ones, for strict proxies.
+
+	// Called from the read pump for frames pushed by the server without an id other than events,
+	// it must not block nor send requests. Revoked subscriptions are dropped before it's called.
+	OnNotification func(*Notification)
 
-	send     chan *responseQueue
-	response chan *responseMessage
+	wire   Codec             // codec negotiated on the last connection
+	dialer *websocket.Dialer // set by WithDialer
+	event  chan<- *EventMessage
+
+	send chan *responseQueue // requests written by the connected session
 
 	done chan struct{}
 
 	sync.Mutex
 	subscriptions map[EventType]uint64
+	running       bool  // configuration is frozen, see Apply
+	batchSize     int   // current batch size hint, lowered by the oversize recovery
+	err           error // error which closed the last connection
+	watchdogs     map[EventType]*watchdog
+
+	consumerLock sync.Mutex // serializes subscription changes made by consumers and Seek
+	consumers    map[*Consumer]struct{}
+	refs         map[EventType]*subscriptionRefs
+	seeking      map[EventType]string // event type -> ID of the pending subscribe request
+
+	sinks     []*sinkPump
+	sinkGroup sync.WaitGroup
+
+	counters *counters
+	lag      *LagTracker
+
+	log    Logger              // frozen by start
+	redact map[string]struct{} // frozen by start
 }
 
+// NewEventListener creates a listener with the default configuration, see New to configure it with options.
 func NewEventListener(addr string, event chan<- *EventMessage) *EventListener {
 	return &EventListener{
+		Name:             cuid.Slug(),
 		Addr:             addr,
 		Token:            "",
 		MessageSizeLimit: messageSizeLimit,
@@ -55,15 +107,23 @@ func NewEventListener(addr string, event chan<- *EventMessage) *EventListener {
 		PongWait:         pongWait,
 		PingPeriod:       pingPeriod,
 		ResponseWait:     responseWait,
+		MaxInFlight:      maxInFlight,
+		RangeIdleWait:    rangeIdleWait,
+		CompressionLevel: compressionLevel,
 
 		ReconnectionDelay:    reconnectionDelay,
 		ReconnectionAttempts: reconnectionAttempts,
 
 		event:         event,
 		send:          make(chan *responseQueue),
-		response:      make(chan *responseMessage),
 		subscriptions: make(map[EventType]uint64),
+		consumers:     make(map[*Consumer]struct{}),
+		refs:          make(map[EventType]*subscriptionRefs),
+		seeking:       make(map[EventType]string),
+		watchdogs:     make(map[EventType]*watchdog),
 		done:          make(chan struct{}),
+		counters:      new(counters),
+		lag:           NewLagTracker(),
 	}
 }
 
@@ -74,119 +134,229 @@ func (e *EventListener) SetToken(token string) {
 
 // ListenAndServe starts the action listener. Returns an error if unable to connect.
 // This method is non-blocking but does not support reconnections. If you need to maintain a connection, use Run
+// A listener is started once, by ListenAndServe or Run, otherwise ErrRunning is returned.
 func (e *EventListener) ListenAndServe(parentContext context.Context) error {
-	u := url.URL{Scheme: "ws", Host: e.Addr, Path: "/"}
+	if err := e.start(); err != nil {
+		return err
+	}
 
-	var err error
-	e.conn, _, err = websocket.DefaultDialer.DialContext(parentContext, u.String(), nil)
+	s, err := e.connect(parentContext)
 	if err != nil {
+		e.stop()
 		return err
 	}
 
-	go func() { _ = e.readPump(parentContext) }()
-	go func() { _ = e.writePump(parentContext) }()
+	go func() { _ = s.readPump(parentContext) }()
+	go func() { _ = s.writePump(parentContext) }()
 	return nil
 }
 
+// Err returns the error which closed the last connection, e.g. ErrMessageTooLarge.
+func (e *EventListener) Err() error {
+	e.Lock()
+	defer e.Unlock()
+	return e.err
+}
+
+func (e *EventListener) setErr(err error) {
+	e.Lock()
+	e.err = err
+	e.Unlock()
+}
+
+// batchSizeHint returns the batch size hint sent on subscribe.
+func (e *EventListener) batchSizeHint() int {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.batchSize > 0 {
+		return e.batchSize
+	}
+	return e.BatchSize
+}
+
+func (e *EventListener) url() string {
+	u := url.URL{Scheme: "ws", Host: e.Addr, Path: "/"}
+	return u.String()
+}
+
+// dial connects to the server offering the subprotocol of the codec, falls back to JSON if the server doesn't accept it.
+func (e *EventListener) dial(ctx context.Context) (*websocket.Conn, Codec, error) {
+	codec := e.Codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+
+	dialer := *websocket.DefaultDialer
+	if e.dialer != nil {
+		dialer = *e.dialer
+	}
+	if dial := dialer.NetDial; dial != nil && dialer.NetDialContext == nil {
+		dialer.NetDialContext = func(_ context.Context, network, addr string) (net.Conn, error) {
+			return dial(network, addr)
+		}
+	}
+	dialer.NetDialContext = e.counters.dialContext(dialer.NetDialContext)
+	dialer.EnableCompression = e.EnableCompression
+	if subprotocol := codec.Subprotocol(); subprotocol != "" {
+		dialer.Subprotocols = []string{subprotocol}
+	}
+
+	conn, _, err := dialer.DialContext(ctx, e.url(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if e.EnableCompression {
+		if err := conn.SetCompressionLevel(e.CompressionLevel); err != nil {
+			_ = conn.Close()
+			return nil, nil, err
+		}
+	}
+
+	if conn.Subprotocol() != codec.Subprotocol() {
+		codec = JSONCodec
+	}
+
+	return conn, codec, nil
+}
+
+// wireCodec returns the codec of the last connection.
+func (e *EventListener) wireCodec() Codec {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.wire == nil {
+		return JSONCodec
+	}
+	return e.wire
+}
+
 func (e *EventListener) Subscribe(eventType EventType, offset uint64) (bool, error) {
+	return e.subscribe(e.newSubscribeMessage(eventType, offset), eventType, offset)
+}
+
+func (e *EventListener) newSubscribeMessage(eventType EventType, offset uint64) *requestMessage {
 	params := struct {
-		Token  string `json:"token"`
-		Topic  string `json:"topic"`
-		Offset uint64 `json:"offset"`
+		Token     string `json:"token"`
+		Topic     string `json:"topic"`
+		Offset    uint64 `json:"offset"`
+		BatchSize int    `json:"batch_size,omitempty"`
 	}{
 		e.Token,
 		eventType.ToString(),
 		offset,
+		e.batchSizeHint(),
 	}
 
-	request := newRequestMessage(methodSubscribe, params)
-	response, err := e.sendRequest(request)
-	if err != nil {
-		return false, err
-	}
+	return newRequestMessage(methodSubscribe, params)
+}
 
-	if response.Error != nil {
-		return false, errors.New(response.Error.Message)
-	}
+func (e *EventListener) subscribe(request *requestMessage, eventType EventType, offset uint64) (bool, error) {
+	// Events may follow the response before sendRequest returns, so track the offset in advance
+	restore := e.trackOffset([]EventType{eventType}, offset)
 
 	result := false
-	err = json.Unmarshal(response.Result, &result)
+	err := e.call(context.Background(), nil, request, &result)
+	if err != nil || !result {
+		restore()
+	}
+
+	return result, err
+}
+
+func (e *EventListener) Unsubscribe(eventType EventType) (bool, error) {
+	result, err := e.unsubscribe(eventType)
 
 	if err == nil && result {
 		e.Lock()
-		e.subscriptions[eventType] = offset
+		delete(e.subscriptions, eventType)
 		e.Unlock()
 	}
 
 	return result, err
 }
 
-func (e *EventListener) Unsubscribe(eventType EventType) (bool, error) {
+// unsubscribe unsubscribes on the server and leaves the tracked offset to the caller.
+func (e *EventListener) unsubscribe(eventType EventType) (bool, error) {
 	params := struct {
 		Topic string `json:"topic"`
 	}{
 		eventType.ToString(),
 	}
 
-	request := newRequestMessage(methodUnsubscribe, params)
-	response, err := e.sendRequest(request)
-	if err != nil {
-		return false, err
-	}
-
-	if response.Error != nil {
-		return false, errors.New(response.Error.Message)
-	}
-
 	result := false
-	err = json.Unmarshal(response.Result, &result)
+	err := e.call(context.Background(), nil, newRequestMessage(methodUnsubscribe, params), &result)
+	return result, err
+}
 
-	if err == nil && result {
-		e.Lock()
-		delete(e.subscriptions, eventType)
-		e.Unlock()
-	}
+// subscribed reports whether the listener is subscribed to eventType, directly or by a consumer.
+func (e *EventListener) subscribed(eventType EventType) bool {
+	e.Lock()
+	defer e.Unlock()
 
-	return result, err
+	_, ok := e.subscriptions[eventType]
+	return ok
 }
 
 func (e *EventListener) BatchSubscribe(eventTypes []EventType, offset uint64) (bool, error) {
+	return e.batchSubscribe(nil, eventTypes, offset)
+}
+
+// batchSubscribe subscribes on the session, or on any connection if the session is nil.
+func (e *EventListener) batchSubscribe(s *session, eventTypes []EventType, offset uint64) (bool, error) {
 	params := struct {
-		Token  string   `json:"token"`
-		Topics []string `json:"topics"`
-		Offset uint64   `json:"offset"`
+		Token     string   `json:"token"`
+		Topics    []string `json:"topics"`
+		Offset    uint64   `json:"offset"`
+		BatchSize int      `json:"batch_size,omitempty"`
 	}{
 		e.Token,
 		make([]string, len(eventTypes)),
 		offset,
+		e.batchSizeHint(),
 	}
 
 	for i, eventType := range eventTypes {
 		params.Topics[i] = eventType.ToString()
 	}
 
-	request := newRequestMessage(methodBatchSubscribe, params)
-	response, err := e.sendRequest(request)
-	if err != nil {
-		return false, err
-	}
+	restore := e.trackOffset(eventTypes, offset)
 
-	if response.Error != nil {
-		return false, errors.New(response.Error.Message)
+	result := false
+	err := e.call(context.Background(), s, newRequestMessage(methodBatchSubscribe, params), &result)
+	if err != nil || !result {
+		restore()
 	}
 
-	result := false
-	err = json.Unmarshal(response.Result, &result)
+	return result, err
+}
 
-	if err == nil && result {
+// trackOffset sets the tracked offset of the event types and returns a function restoring the previous state.
+func (e *EventListener) trackOffset(eventTypes []EventType, offset uint64) func() {
+	e.Lock()
+	defer e.Unlock()
+
+	previous := make(map[EventType]uint64)
+	for _, eventType := range eventTypes {
+		if previousOffset, ok := e.subscriptions[eventType]; ok {
+			previous[eventType] = previousOffset
+		}
+		e.subscriptions[eventType] = offset
+	}
+
+	return func() {
 		e.Lock()
+		defer e.Unlock()
+
 		for _, eventType := range eventTypes {
-			e.subscriptions[eventType] = offset
+			if previousOffset, ok := previous[eventType]; ok {
+				e.subscriptions[eventType] = previousOffset
+			} else {
+				delete(e.subscriptions, eventType)
+			}
 		}
-		e.Unlock()
 	}
-
-	return result, err
 }
 
 func (e *EventListener) BatchUnsubscribe(eventTypes []EventType) (bool, error) {
@@ -200,18 +370,8 @@ func (e *EventListener) BatchUnsubscribe(eventTypes []EventType) (bool, error) {
 		params.Topics[i] = eventType.ToString()
 	}
 
-	request := newRequestMessage(methodBatchUnsubscribe, params)
-	response, err := e.sendRequest(request)
-	if err != nil {
-		return false, err
-	}
-
-	if response.Error != nil {
-		return false, errors.New(response.Error.Message)
-	}
-
 	result := false
-	err = json.Unmarshal(response.Result, &result)
+	err := e.call(context.Background(), nil, newRequestMessage(methodBatchUnsubscribe, params), &result)
 
 	if err == nil && result {
 		e.Lock()
@@ -225,8 +385,12 @@ func (e *EventListener) BatchUnsubscribe(eventTypes []EventType) (bool, error) {
 }
 
 // Close called in Run, use if calling ListenAndServe in defer block.
-// See client example
+// See client example. It waits for the sinks to drain their queues, see AddSink.
 func (e *EventListener) Close() {
 	close(e.done)
-	close(e.event)
+	e.closeConsumers()
+	if e.event != nil {
+		close(e.event)
+	}
+	e.sinkGroup.Wait()
 }
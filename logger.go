@@ -2,15 +2,102 @@ package eventlistener
 
 import "go.uber.org/zap"
 
-var messageLog = zap.NewNop()
-var pumpsLog = zap.NewNop()
+// Logger is the structured logger of a listener, fields are alternating keys and values.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+	// With returns a logger adding the fields to every line.
+	With(keysAndValues ...interface{}) Logger
+	// Named returns a logger of a component, e.g. a pump.
+	Named(name string) Logger
+}
+
+// debugLogger is used by the listeners without a logger of their own.
+var debugLogger Logger = NopLogger()
 
 func EnableDebugLogging() {
 	logger, _ := zap.NewDevelopment()
 	SetDebugLogger(logger)
 }
 
+// SetDebugLogger sets the logger of the listeners without EventListener.Logger, created afterwards.
 func SetDebugLogger(l *zap.Logger) {
-	messageLog = l
-	pumpsLog = l
+	debugLogger = NewZapLogger(l)
+}
+
+type zapLogger struct {
+	logger *zap.SugaredLogger
+}
+
+func NewZapLogger(logger *zap.Logger) Logger {
+	return &zapLogger{logger: logger.WithOptions(zap.AddCallerSkip(1)).Sugar()}
+}
+
+func (l *zapLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.logger.Debugw(msg, keysAndValues...)
+}
+
+func (l *zapLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Infow(msg, keysAndValues...)
+}
+
+func (l *zapLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.logger.Errorw(msg, keysAndValues...)
+}
+
+func (l *zapLogger) With(keysAndValues ...interface{}) Logger {
+	return &zapLogger{logger: l.logger.With(keysAndValues...)}
+}
+
+func (l *zapLogger) Named(name string) Logger {
+	return &zapLogger{logger: l.logger.Named(name)}
+}
+
+type nopLogger struct{}
+
+// NopLogger returns a logger discarding everything.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+
+func (nopLogger) Info(string, ...interface{}) {}
+
+func (nopLogger) Error(string, ...interface{}) {}
+
+func (l nopLogger) With(...interface{}) Logger {
+	return l
+}
+
+func (l nopLogger) Named(string) Logger {
+	return l
+}
+
+// logger returns the logger of the listener with its identity and endpoint.
+func (e *EventListener) logger() Logger {
+	log, _ := e.logging()
+	return log
+}
+
+// logging returns the logger and the redacted keys. They follow the configuration until
+// the listener is started, then they are frozen with it by start.
+func (e *EventListener) logging() (Logger, map[string]struct{}) {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.log != nil {
+		return e.log, e.redact
+	}
+	return e.newLogger(), e.redactKeys()
+}
+
+// newLogger must be called with the lock held.
+func (e *EventListener) newLogger() Logger {
+	logger := e.Logger
+	if logger == nil {
+		logger = debugLogger
+	}
+	return logger.With("listener", e.Name, "addr", e.Addr)
 }
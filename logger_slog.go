@@ -0,0 +1,51 @@
+//go:build go1.21
+
+package eventlistener
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	base   *slog.Logger // without the name
+	name   string
+	logger *slog.Logger
+}
+
+// NewSlogLogger adapts a log/slog logger, names of components are logged as the "logger" attribute.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return newSlogLogger(logger, "")
+}
+
+func newSlogLogger(base *slog.Logger, name string) *slogLogger {
+	logger := base
+	if name != "" {
+		logger = base.With("logger", name)
+	}
+	return &slogLogger{base: base, name: name, logger: logger}
+}
+
+func (l *slogLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelDebug, msg, keysAndValues...)
+}
+
+func (l *slogLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelInfo, msg, keysAndValues...)
+}
+
+func (l *slogLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelError, msg, keysAndValues...)
+}
+
+func (l *slogLogger) With(keysAndValues ...interface{}) Logger {
+	return newSlogLogger(l.base.With(keysAndValues...), l.name)
+}
+
+// Named joins the names like zap, e.g. "reconnect.readPump".
+func (l *slogLogger) Named(name string) Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	return newSlogLogger(l.base, name)
+}
@@ -0,0 +1,36 @@
+//go:build go1.21
+
+package eventlistener
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	handler := slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelInfo})
+	logger := NewSlogLogger(slog.New(handler)).Named("reconnect").With("listener", "a").Named("readPump")
+
+	logger.Debug("skipped")
+	request := []byte(`{"id":"1","method":"subscribe","params":{"token":"t"}}`)
+	logger.Info("running", "attempt", 2, "request", loggedFrame{JSONCodec, request, map[string]struct{}{"token": {}}})
+	logger.Error("failed")
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &line))
+	assert.Equal(t, "running", line["msg"])
+	assert.Equal(t, "reconnect.readPump", line["logger"])
+	assert.Equal(t, "a", line["listener"])
+	assert.Equal(t, float64(2), line["attempt"])
+	assert.Equal(t, "[REDACTED]", line["request"].(map[string]interface{})["params"].(map[string]interface{})["token"])
+	assert.Contains(t, lines[1], `"level":"ERROR"`)
+}
@@ -0,0 +1,168 @@
+package eventlistener
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"strings"
+	"testing"
+)
+
+func TestEventListener_Logger(t *testing.T) {
+	server := newTestServer(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	listener := NewEventListener(server.addr(), nil)
+	listener.Name = "monitor-1"
+	listener.Logger = NewZapLogger(zap.New(core))
+	listener.Token = "secret-token"
+
+	require.NoError(t, listener.ListenAndServe(context.Background()))
+	defer listener.Close()
+
+	ok, err := listener.Subscribe(1, offset)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	request := logs.FilterMessage("sendRequest").All()
+	require.Len(t, request, 1)
+
+	fields := request[0].ContextMap()
+	assert.Equal(t, "monitor-1", fields["listener"])
+	assert.Equal(t, server.addr(), fields["addr"])
+	assert.Contains(t, fields["request"], `"token":"[REDACTED]"`)
+	assert.Contains(t, fields["request"], `"topic":"event_1"`)
+
+	for _, entry := range logs.All() {
+		for _, value := range entry.ContextMap() {
+			assert.NotContains(t, value, "secret-token")
+		}
+	}
+}
+
+func TestEventListener_LoggerApply(t *testing.T) {
+	server := newTestServer(t)
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	// The sink pump runs before the logger is applied
+	sink := &recordSink{messages: make(chan *EventMessage, 1), closed: make(chan struct{})}
+	listener := New(server.addr(), WithSink(sink))
+	require.NoError(t, listener.Apply(WithLogger(NewZapLogger(zap.New(core))), WithName("monitor-2")))
+
+	require.NoError(t, listener.ListenAndServe(context.Background()))
+	ok, err := listener.Subscribe(1, offset)
+	require.NoError(t, err)
+	require.True(t, ok)
+	listener.Close()
+	<-sink.closed
+
+	request := logs.FilterMessage("sendRequest").All()
+	require.Len(t, request, 1)
+	assert.Equal(t, "monitor-2", request[0].ContextMap()["listener"])
+
+	sinkPump := 0
+	for _, entry := range logs.FilterMessage(msgPumpStopped).All() {
+		if entry.LoggerName == "sinkPump" {
+			sinkPump++
+			assert.Equal(t, "monitor-2", entry.ContextMap()["listener"])
+		}
+	}
+	assert.Equal(t, 1, sinkPump)
+}
+
+func TestZapLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := NewZapLogger(zap.New(core)).With("listener", "a").Named("readPump")
+
+	logger.Debug("skipped")
+	logger.Info("running", "attempt", 2)
+	logger.Error("failed")
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "readPump", entries[0].LoggerName)
+	assert.Equal(t, map[string]interface{}{"listener": "a", "attempt": int64(2)}, entries[0].ContextMap())
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+}
+
+func TestNopLogger(t *testing.T) {
+	logger := NopLogger().With("a", 1).Named("b")
+	logger.Debug("x")
+	logger.Info("x")
+	logger.Error("x")
+	assert.Equal(t, NopLogger(), logger)
+}
+
+func TestRedactJSON(t *testing.T) {
+	keys := map[string]struct{}{"token": {}, "seed": {}}
+	message, err := redactJSON([]byte(`{"params":{"token":"t","nested":[{"seed":1}],"offset":18446744073709551615}}`), keys)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"params":{"token":"[REDACTED]","nested":[{"seed":"[REDACTED]"}],"offset":18446744073709551615}}`, string(message))
+	assert.False(t, strings.Contains(string(message), `"t"`))
+
+	_, err = redactJSON([]byte(`{`), keys)
+	assert.Error(t, err)
+}
+
+func TestEventListener_RedactKeys(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec} {
+		core, logs := observer.New(zapcore.DebugLevel)
+
+		events := make(chan *EventMessage, 1)
+		listener := NewEventListener("", events)
+		listener.Logger = NewZapLogger(zap.New(core))
+		listener.RedactKeys = []string{"seed"}
+
+		frame, err := encodeFrame(codec, map[string]interface{}{
+			"result": map[string]interface{}{
+				"offset": 1,
+				"events": []interface{}{map[string]interface{}{"offset": 1, "data": map[string]interface{}{"seed": "s3cr3t", "amount": 5}}},
+			},
+		})
+		require.NoError(t, err)
+		_, err = listener.processMessage(codec, frame)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"seed":"s3cr3t","amount":5}`, string((<-events).Events[0].Data))
+
+		entries := logs.FilterMessage("processMessage").All()
+		require.Len(t, entries, 1)
+		logged := entries[0].ContextMap()["response"].(string)
+		assert.Contains(t, logged, `"seed":"[REDACTED]"`)
+		assert.Contains(t, logged, `"amount":5`)
+		assert.NotContains(t, logged, "s3cr3t")
+	}
+}
+
+func TestEventListener_RedactKeysApply(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	events := make(chan *EventMessage, 1)
+	sink := &recordSink{messages: make(chan *EventMessage, 2), closed: make(chan struct{})}
+	listener := New("", WithEvents(events), WithSink(sink), WithLogger(NewZapLogger(zap.New(core))))
+	defer listener.Close()
+	require.NoError(t, listener.Apply(WithRedactKeys("password")))
+
+	frame := []byte(`{"result":{"offset":1,"events":[{"offset":1,"data":{"password":"s3cr3t"}}]}}`)
+	_, err := listener.processMessage(JSONCodec, frame)
+	require.NoError(t, err)
+	<-events
+
+	// Set directly, e.g. after NewBroadcaster
+	listener.RedactKeys = append(listener.RedactKeys, "pin")
+	frame = []byte(`{"result":{"offset":2,"events":[{"offset":2,"data":{"pin":"1234"}}]}}`)
+	_, err = listener.processMessage(JSONCodec, frame)
+	require.NoError(t, err)
+	<-events
+
+	entries := logs.FilterMessage("processMessage").All()
+	require.Len(t, entries, 2)
+	assert.Contains(t, entries[0].ContextMap()["response"], `"password":"[REDACTED]"`)
+	assert.Contains(t, entries[1].ContextMap()["response"], `"pin":"[REDACTED]"`)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.ContextMap()["response"], "s3cr3t")
+		assert.NotContains(t, entry.ContextMap()["response"], "1234")
+	}
+}
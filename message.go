@@ -1,10 +1,10 @@
 package eventlistener
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"github.com/gorilla/websocket"
 	"github.com/lucsky/cuid"
-	"go.uber.org/zap"
 	"time"
 )
 
@@ -21,35 +21,66 @@ type requestMessage struct {
 	Params interface{} `json:"params"`
 }
 
-func (req *requestMessage) toJSON() ([]byte, error) {
-	return json.Marshal(req)
+// encode encodes the request, as a JSON-RPC 2.0 request in strict mode.
+func (req *requestMessage) encode(codec Codec, strict bool) ([]byte, error) {
+	if strict {
+		return codec.Marshal(&strictRequestMessage{jsonrpcVersion, req.ID, req.Method, req.Params})
+	}
+	return codec.Marshal(req)
+}
+
+// CodeMethodNotFound is the JSON-RPC error code of a method the server doesn't support.
+const CodeMethodNotFound = -32601
+
+// RPCError is an error response of the server.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"` // additional information, JSON whatever the codec
+
+	invalid string // member missing from the error object, rejected in strict mode
 }
 
-type responseErrorMessage struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+func (e *RPCError) Error() string {
+	return e.Message
 }
 
 type responseMessage struct {
-	ID     *string               `json:"id"`
-	Result json.RawMessage       `json:"result"`
-	Error  *responseErrorMessage `json:"error"`
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *string         `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *RPCError       `json:"error"`
+
+	// Frames pushed by the server without an id, other than events, see Notification
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+
+	err error // set in strict mode if the response is invalid, returned to the caller
 }
 
 type responseQueue struct {
 	ID       string
-	message  []byte
+	request  *requestMessage
+	session  *session // session the request is bound to, nil for any
+	deadline time.Time
 	response chan *responseMessage
+	err      error // set if the request failed, before response is closed
 }
 
-func newResponseQueue(ID string, message []byte) *responseQueue {
+func newResponseQueue(request *requestMessage) *responseQueue {
 	return &responseQueue{
-		ID:       ID,
-		message:  message,
-		response: make(chan *responseMessage),
+		ID:       request.ID,
+		request:  request,
+		response: make(chan *responseMessage, 1), // the responsePump doesn't block if the request timed out
 	}
 }
 
+// fail closes the response of a request which could not be sent or timed out.
+func (q *responseQueue) fail(err error) {
+	q.err = err
+	close(q.response)
+}
+
 func newRequestMessage(method string, params interface{}) *requestMessage {
 	return &requestMessage{
 		ID:     cuid.New(),
@@ -58,63 +89,165 @@ func newRequestMessage(method string, params interface{}) *requestMessage {
 	}
 }
 
-func (e *EventListener) sendRequest(req *requestMessage) (*responseMessage, error) {
-	message, err := req.toJSON()
-	if err != nil {
-		return nil, err
-	}
-	messageLog.Debug("sendRequest", zap.Any("request", json.RawMessage(message)))
+func (e *EventListener) sendRequest(ctx context.Context, req *requestMessage) (*responseMessage, error) {
+	return e.sendSessionRequest(ctx, nil, req)
+}
+
+// sendSessionRequest sends the request on the session, or on any connection if the session is nil.
+// A request bound to a session fails with ConnectionClosed once the session is closed.
+func (e *EventListener) sendSessionRequest(ctx context.Context, s *session, req *requestMessage) (*responseMessage, error) {
+	wait := newResponseQueue(req)
+	wait.session = s
 
-	wait := newResponseQueue(req.ID, message)
+	var closed <-chan struct{}
+	if s != nil {
+		closed = s.done
+	}
 
 	select {
 	case e.send <- wait:
+	case <-closed:
+		return nil, ConnectionClosed
 	case _, ok := <-e.done:
 		if !ok {
 			return nil, ListenerClosed
 		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 
+	// The responsePump closes the response on timeout
 	select {
-	case response := <-wait.response:
+	case response, ok := <-wait.response:
+		if !ok {
+			if wait.err != nil {
+				return nil, wait.err
+			}
+			return nil, ConnectionClosed
+		}
 		return response, nil
-	case <-time.After(e.ResponseWait):
-		return nil, fmt.Errorf("request timeout: %+v", req)
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 }
 
-func (e *EventListener) processMessage(message []byte) error {
-	response := new(responseMessage)
-	if err := json.Unmarshal(message, response); err != nil {
-		return err
+// processMessage handles a message received with the codec, a frame or in strict mode a batch of frames.
+// Events are delivered, responses returned.
+func (e *EventListener) processMessage(codec Codec, message []byte) ([]*responseMessage, error) {
+	if e.StrictJSONRPC {
+		frames, ok, err := splitBatch(codec, message)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			responses := make([]*responseMessage, 0, len(frames))
+			for _, frame := range frames {
+				response, err := e.processFrame(codec, frame)
+				if err != nil {
+					return nil, err
+				}
+				if response != nil {
+					responses = append(responses, response)
+				}
+			}
+			return responses, nil
+		}
+	}
+
+	response, err := e.processFrame(codec, message)
+	if err != nil || response == nil {
+		return nil, err
 	}
-	messageLog.Debug("processMessage", zap.Any("response", json.RawMessage(message)))
+	return []*responseMessage{response}, nil
+}
 
-	if response.ID != nil {
-		e.response <- response
-	} else {
-		eventMessage := new(EventMessage)
-		if err := json.Unmarshal(response.Result, eventMessage); err != nil {
-			return err
+// processFrame handles a frame received with the codec. Events are delivered, responses returned.
+func (e *EventListener) processFrame(codec Codec, message []byte) (*responseMessage, error) {
+	response, eventMessage, err := decodeFrame(codec, message, e.counters)
+	if err != nil {
+		return nil, err
+	}
+	e.logger().Debug("processMessage", "response", e.loggedFrame(codec, message))
+
+	if response != nil {
+		if e.StrictJSONRPC {
+			// An invalid response fails its request only, the connection goes on
+			response.err = response.validate()
+		}
+		if response.ID == nil {
+			if response.err != nil {
+				e.logger().Error("processMessage", "error", response.err)
+				return nil, nil
+			}
+			e.notify(response)
+			return nil, nil
 		}
+		e.seekResponse(*response.ID)
+		return response, nil
+	}
+
+	if !e.dropStale(eventMessage) {
+		return nil, nil
+	}
+	e.stamp(eventMessage)
 
+	// The receiver may release the message, track the offsets before handing it out
+	e.updateOffset(eventMessage.Events)
+	e.lag.Observe(eventMessage)
+	marks := lagMarks(eventMessage)
+
+	if e.exclusive() {
+		eventMessage.pooled = true
+		e.event <- eventMessage
+	} else {
 		if e.event != nil {
 			e.event <- eventMessage
 		}
+		e.dispatch(eventMessage)
+		e.dispatchSinks(eventMessage)
+	}
+
+	e.lag.commitMarks(marks)
+	return nil, nil
+}
+
+// stamp sets the receive time and the server time of the events.
+func (e *EventListener) stamp(message *EventMessage) {
+	message.ReceivedAt = time.Now()
+	for _, event := range message.Events {
+		event.ReceivedAt = message.ReceivedAt
+		if e.Timestamp != nil {
+			event.Timestamp, _ = e.Timestamp(event)
+		}
+	}
+}
+
+// exclusive reports whether the listener channel is the only receiver of events.
+func (e *EventListener) exclusive() bool {
+	e.Lock()
+	defer e.Unlock()
+
+	return e.event != nil && len(e.consumers) == 0 && len(e.sinks) == 0
+}
 
-		e.updateOffset(eventMessage.Events)
+// frameValue logs JSON frames as is and binary ones as bytes.
+func frameValue(codec Codec, message []byte) interface{} {
+	if codec.FrameType() == websocket.TextMessage {
+		return json.RawMessage(message)
 	}
-	return nil
+	return message
 }
 
 func (e *EventListener) updateOffset(events []*Event) {
 	e.Lock()
 	defer e.Unlock()
 
+	now := time.Now()
 	for _, event := range events {
 		event := event
 		if _, ok := e.subscriptions[event.EventType]; ok {
 			e.subscriptions[event.EventType] = event.Offset + 1
+			e.touchWatchdog(event.EventType, now)
 		}
 	}
 }
@@ -1,15 +1,18 @@
 package eventlistener
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
 	"testing"
 )
 
 func TestEventListener_sendRequest(t *testing.T) {
 	listener := &EventListener{
-		send: make(chan *responseQueue),
+		send:         make(chan *responseQueue),
+		ResponseWait: responseWait,
 	}
 
 	rawResult := json.RawMessage("true")
@@ -21,9 +24,149 @@ func TestEventListener_sendRequest(t *testing.T) {
 	}()
 
 	request := newRequestMessage("test", nil)
-	response, err := listener.sendRequest(request)
+	response, err := listener.sendRequest(context.Background(), request)
 	require.NoError(t, err)
 	assert.Equal(t, rawResult, response.Result)
 
-	close(listener.send)
+	// A request bound to a closed session isn't sent
+	s := &session{done: make(chan struct{})}
+	close(s.done)
+	_, err = listener.sendSessionRequest(context.Background(), s, newRequestMessage("test", nil))
+	assert.Equal(t, ConnectionClosed, err)
+}
+
+func TestDecodeJSONFrame(t *testing.T) {
+	response, message, err := decodeJSONFrame([]byte(` {"result": {"a": "}"}, "id" : "1", "error":null} `))
+	require.NoError(t, err)
+	assert.Nil(t, message)
+	assert.Equal(t, "1", *response.ID)
+	assert.Equal(t, `{"a": "}"}`, string(response.Result))
+	assert.Nil(t, response.Error)
+
+	response, _, err = decodeJSONFrame([]byte(`{"id":"2","error":{"code":-1,"message":"topic \"x\" not found"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, &RPCError{Code: -1, Message: `topic "x" not found`}, response.Error)
+
+	response, message, err = decodeJSONFrame([]byte(`{"id":null,"result":{"offset":1,"events":[{"offset":1,"event_type":2,"data":[1,{"b":[]}]}]}}`))
+	require.NoError(t, err)
+	assert.Nil(t, response)
+	assert.Equal(t, uint64(1), message.Offset)
+	require.Len(t, message.Events, 1)
+	assert.Equal(t, EventType(2), message.Events[0].EventType)
+	assert.Equal(t, `[1,{"b":[]}]`, string(message.Events[0].Data))
+
+	for _, frame := range []string{``, `[]`, `{"id"}`, `{"id":"1",}`, `{"id":"1"`, `{"result":"x}`, `{"result":}`} {
+		_, _, err := decodeJSONFrame([]byte(frame))
+		assert.Error(t, err, frame)
+	}
+
+	// Results which aren't event batches are unknown notifications
+	for _, frame := range []string{`{"result":{"offset":"x"}}`, `{"result":"maintenance"}`} {
+		response, message, err := decodeJSONFrame([]byte(frame))
+		require.NoError(t, err, frame)
+		assert.Nil(t, message)
+		require.NotNil(t, response)
+		assert.Nil(t, response.ID)
+		assert.Empty(t, response.Method)
+		assert.Nil(t, response.Error)
+	}
+}
+
+func TestEventMessage_Release(t *testing.T) {
+	frame := []byte(`{"result":{"offset":2,"events":[{"offset":0,"sender":"a","data":{"x":1}},{"offset":1},{"offset":2}]}}`)
+
+	_, message, err := decodeJSONFrame(frame)
+	require.NoError(t, err)
+	message.Release() // not pooled, left to the garbage collector
+	assert.Len(t, message.Events, 3)
+
+	message.pooled = true
+	message.Events = message.Events[1:2]
+	message.Release()
+	assert.Empty(t, message.Events)
+
+	frame = []byte(`{"result":{"offset":5,"events":[{"offset":4},{"offset":5,"data":1}]}}`)
+	for i := 0; i < 10; i++ {
+		_, message, err = decodeJSONFrame(frame)
+		require.NoError(t, err)
+		require.Len(t, message.Events, 2)
+		assert.NotSame(t, message.Events[0], message.Events[1])
+		assert.Equal(t, &Event{Offset: 4}, message.Events[0])
+		assert.Equal(t, &Event{Offset: 5, Data: json.RawMessage(`1`)}, message.Events[1])
+
+		message.pooled = true
+		message.Release()
+	}
+}
+
+func TestEventListener_processMessage_pooled(t *testing.T) {
+	events := make(chan *EventMessage, 1)
+	listener := NewEventListener("", events)
+	listener.subscriptions[1] = 0
+
+	frame := []byte(`{"result":{"offset":3,"events":[{"offset":3,"event_type":1}]}}`)
+	_, err := listener.processMessage(JSONCodec, frame)
+	require.NoError(t, err)
+	message := <-events
+	assert.True(t, message.pooled)
+	assert.Equal(t, uint64(4), listener.subscriptions[1])
+	message.Release()
+
+	listener.AddSink(NewWriterSink(io.Discard))
+	defer listener.Close()
+	_, err = listener.processMessage(JSONCodec, frame)
+	require.NoError(t, err)
+	assert.False(t, (<-events).pooled)
+}
+
+// decodeTwoPass is the decoding before frames were scanned, kept for comparison.
+func decodeTwoPass(message []byte) (*responseMessage, *EventMessage, error) {
+	response := new(responseMessage)
+	if err := json.Unmarshal(message, response); err != nil {
+		return nil, nil, err
+	}
+	if response.ID != nil {
+		return response, nil, nil
+	}
+
+	eventMessage := new(EventMessage)
+	if err := json.Unmarshal(response.Result, eventMessage); err != nil {
+		return nil, nil, err
+	}
+	return nil, eventMessage, nil
+}
+
+// BenchmarkDecodeEvents decodes frames of 100 events, divide allocs/op by 100 for allocations per event.
+func BenchmarkDecodeEvents(b *testing.B) {
+	message := benchmarkFrame(b, JSONCodec)
+
+	b.Run("two-pass", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := decodeTwoPass(message); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("single-pass", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := decodeJSONFrame(message); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, eventMessage, err := decodeJSONFrame(message)
+			if err != nil {
+				b.Fatal(err)
+			}
+			eventMessage.pooled = true
+			eventMessage.Release()
+		}
+	})
 }
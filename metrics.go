@@ -0,0 +1,95 @@
+package eventlistener
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+)
+
+// Metrics is a snapshot of the traffic counters of a listener, accumulated over all its connections.
+type Metrics struct {
+	WireBytesRead    uint64 // Bytes read from the network, handshakes, framing and compressed payloads.
+	WireBytesWritten uint64 // Bytes written to the network.
+	BytesRead        uint64 // Payload bytes of the received messages, after decompression.
+	BytesWritten     uint64 // Payload bytes of the sent messages, before compression.
+	MessagesRead     uint64
+	MessagesWritten  uint64
+	DecodeFallbacks  uint64 // Binary frames decoded by reflection, with values the frame scanner leaves to the codec.
+
+	Lags map[EventType]Lag // Lag of every received event type.
+}
+
+// CompressionRatio returns how many payload bytes were received per byte on the wire, 0 before any traffic.
+func (m Metrics) CompressionRatio() float64 {
+	if m.WireBytesRead == 0 {
+		return 0
+	}
+	return float64(m.BytesRead) / float64(m.WireBytesRead)
+}
+
+type counters struct {
+	wireRead     uint64
+	wireWritten  uint64
+	read         uint64
+	written      uint64
+	messagesRead uint64
+	messagesSent uint64
+
+	decodeFallbacks uint64
+}
+
+func (c *counters) received(n int) {
+	atomic.AddUint64(&c.read, uint64(n))
+	atomic.AddUint64(&c.messagesRead, 1)
+}
+
+func (c *counters) sent(n int) {
+	atomic.AddUint64(&c.written, uint64(n))
+	atomic.AddUint64(&c.messagesSent, 1)
+}
+
+// dialContext wraps the dial function, net.Dialer if nil, to count the bytes on the wire.
+func (c *counters) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = new(net.Dialer).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &countingConn{Conn: conn, counters: c}, nil
+	}
+}
+
+type countingConn struct {
+	net.Conn
+	counters *counters
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddUint64(&c.counters.wireRead, uint64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddUint64(&c.counters.wireWritten, uint64(n))
+	return n, err
+}
+
+// Metrics returns the traffic counters, compare wire and payload bytes to measure compression savings.
+func (e *EventListener) Metrics() Metrics {
+	return Metrics{
+		WireBytesRead:    atomic.LoadUint64(&e.counters.wireRead),
+		WireBytesWritten: atomic.LoadUint64(&e.counters.wireWritten),
+		BytesRead:        atomic.LoadUint64(&e.counters.read),
+		BytesWritten:     atomic.LoadUint64(&e.counters.written),
+		MessagesRead:     atomic.LoadUint64(&e.counters.messagesRead),
+		MessagesWritten:  atomic.LoadUint64(&e.counters.messagesSent),
+		DecodeFallbacks:  atomic.LoadUint64(&e.counters.decodeFallbacks),
+		Lags:             e.lag.Lags(),
+	}
+}
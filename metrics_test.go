@@ -0,0 +1,62 @@
+package eventlistener
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestEventListener_Metrics(t *testing.T) {
+	data := fmt.Sprintf(`{"log":%q}`, strings.Repeat("casino.daobet bet accepted ", 400))
+
+	for _, compression := range []bool{false, true} {
+		compression := compression
+		t.Run(fmt.Sprintf("compression=%v", compression), func(t *testing.T) {
+			server := newTestServer(t)
+			server.compression = true
+			server.publish(1, data)
+
+			parentContext, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			events := make(chan *EventMessage, 1)
+			listener := NewEventListener(server.addr(), events)
+			listener.EnableCompression = compression
+			require.NoError(t, listener.ListenAndServe(parentContext))
+			defer listener.Close()
+
+			ok, err := listener.Subscribe(1, offset)
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.JSONEq(t, data, string(receiveEvent(t, events).Data))
+
+			metrics := listener.Metrics()
+			assert.Equal(t, uint64(2), metrics.MessagesRead)
+			assert.Equal(t, uint64(1), metrics.MessagesWritten)
+			assert.Greater(t, metrics.BytesRead, uint64(len(data)))
+			assert.NotZero(t, metrics.BytesWritten)
+			assert.NotZero(t, metrics.WireBytesWritten)
+
+			if compression {
+				assert.Less(t, metrics.WireBytesRead, metrics.BytesRead/10)
+				assert.Greater(t, metrics.CompressionRatio(), 10.0)
+			} else {
+				assert.Greater(t, metrics.WireBytesRead, metrics.BytesRead)
+				assert.Less(t, metrics.CompressionRatio(), 1.0)
+			}
+		})
+	}
+}
+
+func TestEventListener_CompressionLevel(t *testing.T) {
+	server := newTestServer(t)
+	server.compression = true
+
+	listener := NewEventListener(server.addr(), nil)
+	listener.EnableCompression = true
+	listener.CompressionLevel = 42
+	assert.Error(t, listener.ListenAndServe(context.Background()))
+}
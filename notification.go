@@ -0,0 +1,121 @@
+package eventlistener
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Methods of the notifications with typed handling.
+const (
+	NotificationSubscriptionRevoked = "subscriptionRevoked"
+	NotificationTokenExpired        = "tokenExpired"
+)
+
+var (
+	ErrSubscriptionRevoked = errors.New("subscription revoked")
+	ErrTokenExpired        = errors.New("token expired")
+)
+
+// Notification is a frame pushed by the server without an id which is not an event batch,
+// a notification with a method or an error. A frame with neither, whose result isn't an event batch,
+// is an unknown notification without a method and the result as params. Params are JSON whatever the codec.
+type Notification struct {
+	Method string
+	Params json.RawMessage
+	Error  *RPCError // set for an error pushed by the server
+}
+
+// Revocation is a subscription revoked by the server, e.g. after a permission change.
+type Revocation struct {
+	EventType EventType
+	Reason    string
+}
+
+func (r *Revocation) Error() string {
+	if r.Reason == "" {
+		return fmt.Sprintf("%s: %s", ErrSubscriptionRevoked, r.EventType.ToString())
+	}
+	return fmt.Sprintf("%s: %s: %s", ErrSubscriptionRevoked, r.EventType.ToString(), r.Reason)
+}
+
+// Unwrap makes errors.Is(revocation, ErrSubscriptionRevoked) true.
+func (r *Revocation) Unwrap() error {
+	return ErrSubscriptionRevoked
+}
+
+// Revocation returns the revoked subscription of a subscriptionRevoked notification.
+func (n *Notification) Revocation() (*Revocation, bool) {
+	if n.Method != NotificationSubscriptionRevoked {
+		return nil, false
+	}
+
+	params := struct {
+		Topic  string `json:"topic"`
+		Reason string `json:"reason"`
+	}{}
+	if err := json.Unmarshal(n.Params, &params); err != nil {
+		return nil, false
+	}
+
+	eventType, err := ParseEventType(params.Topic)
+	if err != nil {
+		return nil, false
+	}
+	return &Revocation{EventType: eventType, Reason: params.Reason}, true
+}
+
+// Err returns the notification as a typed error: a *Revocation, ErrTokenExpired or the *RPCError
+// pushed by the server. It returns nil for other notifications.
+func (n *Notification) Err() error {
+	if n.Error != nil {
+		return n.Error
+	}
+	if revocation, ok := n.Revocation(); ok {
+		return revocation
+	}
+	if n.Method == NotificationTokenExpired {
+		return ErrTokenExpired
+	}
+	return nil
+}
+
+// notify handles a frame without an id which is not an event batch.
+// A revoked subscription is dropped, so it isn't restored on reconnection.
+func (e *EventListener) notify(frame *responseMessage) {
+	notification := &Notification{Method: frame.Method, Params: frame.Params, Error: frame.Error}
+	log := e.logger().Named("notify")
+
+	if revocation, ok := notification.Revocation(); ok {
+		e.revoke(revocation.EventType)
+	}
+
+	if err := notification.Err(); err != nil {
+		log.Error("server notification", "method", notification.Method, "error", err)
+	} else if notification.Method == "" {
+		log.Info("unknown server notification", "size", len(notification.Params))
+	} else {
+		log.Info("server notification", "method", notification.Method)
+	}
+
+	if e.OnNotification != nil {
+		e.OnNotification(notification)
+	}
+}
+
+// revoke drops the subscription of the event type, also for the consumers, and stops its watchdog.
+func (e *EventListener) revoke(eventType EventType) {
+	e.Unwatch(eventType)
+
+	e.Lock()
+	defer e.Unlock()
+
+	delete(e.subscriptions, eventType)
+	delete(e.seeking, eventType)
+	delete(e.refs, eventType)
+	for c := range e.consumers {
+		c.Lock()
+		delete(c.eventTypes, eventType)
+		c.Unlock()
+	}
+}
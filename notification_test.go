@@ -0,0 +1,169 @@
+package eventlistener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDecodeFrame_notifications(t *testing.T) {
+	for _, codec := range []Codec{JSONCodec, MsgpackCodec, textCodec{}} {
+		codec := codec
+		t.Run(fmt.Sprintf("%T", codec), func(t *testing.T) {
+			decode := func(frame interface{}) (*responseMessage, *EventMessage) {
+				message, err := encodeFrame(codec, frame)
+				require.NoError(t, err)
+				response, eventMessage, err := decodeFrame(codec, message, nil)
+				require.NoError(t, err)
+				return response, eventMessage
+			}
+
+			response, eventMessage := decode(map[string]interface{}{
+				"method": NotificationSubscriptionRevoked,
+				"params": map[string]string{"topic": "event_1", "reason": "forbidden"},
+			})
+			assert.Nil(t, eventMessage)
+			require.NotNil(t, response)
+			assert.Nil(t, response.ID)
+			assert.Equal(t, NotificationSubscriptionRevoked, response.Method)
+			assert.JSONEq(t, `{"topic":"event_1","reason":"forbidden"}`, string(response.Params))
+
+			response, eventMessage = decode(map[string]interface{}{
+				"id":    nil,
+				"error": &RPCError{Code: -32000, Message: "shutting down"},
+			})
+			assert.Nil(t, eventMessage)
+			require.NotNil(t, response)
+			assert.Nil(t, response.ID)
+			assert.Equal(t, &RPCError{Code: -32000, Message: "shutting down"}, response.Error)
+
+			response, eventMessage = decode(map[string]interface{}{
+				"result": &EventMessage{Offset: 1, Events: []*Event{{Offset: 1, EventType: 1}}},
+				"error":  nil,
+			})
+			assert.Nil(t, response)
+			require.NotNil(t, eventMessage)
+			assert.Equal(t, uint64(1), eventMessage.Offset)
+
+			response, eventMessage = decode(map[string]interface{}{"result": "maintenance"})
+			assert.Nil(t, eventMessage)
+			require.NotNil(t, response)
+			assert.Nil(t, response.ID)
+			assert.Empty(t, response.Method)
+			assert.JSONEq(t, `"maintenance"`, string(response.Params))
+		})
+	}
+}
+
+func TestNotification_Err(t *testing.T) {
+	notification := &Notification{Method: NotificationSubscriptionRevoked, Params: []byte(`{"topic":"event_3","reason":"forbidden"}`)}
+	revocation, ok := notification.Revocation()
+	require.True(t, ok)
+	assert.Equal(t, &Revocation{EventType: 3, Reason: "forbidden"}, revocation)
+	assert.True(t, errors.Is(notification.Err(), ErrSubscriptionRevoked))
+	assert.EqualError(t, notification.Err(), "subscription revoked: event_3: forbidden")
+
+	notification = &Notification{Method: NotificationSubscriptionRevoked, Params: []byte(`{"topic":"x"}`)}
+	_, ok = notification.Revocation()
+	assert.False(t, ok)
+
+	notification = &Notification{Method: NotificationTokenExpired}
+	assert.Equal(t, ErrTokenExpired, notification.Err())
+
+	notification = &Notification{Error: &RPCError{Code: -32000, Message: "shutting down"}}
+	assert.Equal(t, notification.Error, notification.Err())
+
+	notification = &Notification{Method: "maintenance"}
+	assert.NoError(t, notification.Err())
+}
+
+func TestEventListener_revocation(t *testing.T) {
+	server := newTestServer(t)
+	events := make(chan *EventMessage, 10)
+	notifications := make(chan *Notification, 10)
+
+	parentContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener := New(server.addr(),
+		WithEvents(events),
+		WithNotificationHandler(func(notification *Notification) {
+			notifications <- notification
+		}),
+	)
+	require.NoError(t, listener.ListenAndServe(parentContext))
+
+	consumer := listener.NewConsumer(make(chan *EventMessage, 10))
+	ok, err := consumer.Subscribe(1, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = listener.Subscribe(2, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	server.notify(NotificationSubscriptionRevoked, map[string]string{"topic": "event_1", "reason": "forbidden"})
+
+	select {
+	case notification := <-notifications:
+		revocation, ok := notification.Revocation()
+		require.True(t, ok)
+		assert.Equal(t, EventType(1), revocation.EventType)
+	case <-time.After(waitEventsTimeout):
+		t.Fatal("no notification")
+	}
+
+	listener.Lock()
+	_, subscribed := listener.subscriptions[1]
+	listener.Unlock()
+	assert.False(t, subscribed)
+	assert.Empty(t, consumer.EventTypes())
+
+	// The connection is kept and other subscriptions go on
+	server.publish(2, `{"x":1}`)
+	event := receiveEvent(t, events)
+	assert.Equal(t, EventType(2), event.EventType)
+
+	var result map[string]int
+	require.NoError(t, listener.Call(context.Background(), "echo", map[string]int{"a": 1}, &result))
+	assert.Equal(t, map[string]int{"a": 1}, result)
+}
+
+func TestEventListener_unknownNotification(t *testing.T) {
+	server := newTestServer(t)
+	events := make(chan *EventMessage, 10)
+	notifications := make(chan *Notification, 10)
+
+	parentContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener := New(server.addr(),
+		WithEvents(events),
+		WithNotificationHandler(func(notification *Notification) {
+			notifications <- notification
+		}),
+	)
+	require.NoError(t, listener.ListenAndServe(parentContext))
+
+	ok, err := listener.Subscribe(1, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	server.push(map[string]string{"result": "maintenance"})
+
+	select {
+	case notification := <-notifications:
+		assert.Empty(t, notification.Method)
+		assert.JSONEq(t, `"maintenance"`, string(notification.Params))
+		assert.NoError(t, notification.Err())
+	case <-time.After(waitEventsTimeout):
+		t.Fatal("no notification")
+	}
+
+	// The connection is kept
+	server.publish(1, `{"x":1}`)
+	assert.Equal(t, EventType(1), receiveEvent(t, events).EventType)
+}
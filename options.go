@@ -0,0 +1,241 @@
+package eventlistener
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"time"
+)
+
+var ErrRunning = errors.New("listener is running")
+
+// Option configures a listener created by New, see Apply.
+type Option func(*options)
+
+type options struct {
+	listener *EventListener
+	sinks    []Sink // added once all options are applied, so the sink pumps log with the final logger
+}
+
+// New creates a listener configured by the options, applied in order on top of the defaults of NewEventListener.
+func New(addr string, opts ...Option) *EventListener {
+	e := NewEventListener(addr, nil)
+	_ = e.Apply(opts...)
+	return e
+}
+
+// Apply configures the listener. The configuration is frozen once the listener is started
+// by ListenAndServe or Run, afterwards Apply returns ErrRunning and leaves the listener unchanged.
+// The options are applied under the lock, so a concurrent start waits for them.
+func (e *EventListener) Apply(opts ...Option) error {
+	e.Lock()
+	if e.running {
+		e.Unlock()
+		return ErrRunning
+	}
+
+	o := &options{listener: e}
+	for _, opt := range opts {
+		opt(o)
+	}
+	e.Unlock()
+
+	// Sinks may be added to a running listener, the pumps log with the applied logger
+	for _, sink := range o.sinks {
+		e.AddSink(sink)
+	}
+	return nil
+}
+
+// start freezes the configuration, returns ErrRunning if the listener is already started.
+func (e *EventListener) start() error {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.running {
+		return ErrRunning
+	}
+	e.running = true
+	e.log, e.redact = e.newLogger(), e.redactKeys()
+	return nil
+}
+
+// stop allows to start the listener again after it failed to connect.
+func (e *EventListener) stop() {
+	e.Lock()
+	e.running = false
+	e.log, e.redact = nil, nil
+	e.Unlock()
+}
+
+func WithName(name string) Option {
+	return func(o *options) {
+		o.listener.Name = name
+	}
+}
+
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.listener.Token = token
+	}
+}
+
+func WithMessageSizeLimit(limit int64) Option {
+	return func(o *options) {
+		o.listener.MessageSizeLimit = limit
+	}
+}
+
+func WithWriteWait(wait time.Duration) Option {
+	return func(o *options) {
+		o.listener.WriteWait = wait
+	}
+}
+
+// WithPongWait sets the pong wait and the ping period to 9/10 of it, use WithPingPeriod afterwards for another period.
+func WithPongWait(wait time.Duration) Option {
+	return func(o *options) {
+		o.listener.PongWait = wait
+		o.listener.PingPeriod = (wait * 9) / 10
+	}
+}
+
+func WithPingPeriod(period time.Duration) Option {
+	return func(o *options) {
+		o.listener.PingPeriod = period
+	}
+}
+
+func WithResponseWait(wait time.Duration) Option {
+	return func(o *options) {
+		o.listener.ResponseWait = wait
+	}
+}
+
+// WithMaxInFlight sets how many requests are written without waiting for their responses.
+func WithMaxInFlight(max int) Option {
+	return func(o *options) {
+		o.listener.MaxInFlight = max
+	}
+}
+
+func WithRangeIdleWait(wait time.Duration) Option {
+	return func(o *options) {
+		o.listener.RangeIdleWait = wait
+	}
+}
+
+// WithReconnection sets the delay between connection attempts of Run and the number of attempts.
+func WithReconnection(delay time.Duration, attempts int) Option {
+	return func(o *options) {
+		o.listener.ReconnectionDelay = delay
+		o.listener.ReconnectionAttempts = attempts
+	}
+}
+
+// WithOversizeRecovery enables the oversize recovery starting from the batch size hint, 0 leaves it to the server.
+func WithOversizeRecovery(batchSize int) Option {
+	return func(o *options) {
+		o.listener.BatchSize = batchSize
+		o.listener.OversizeRecovery = true
+	}
+}
+
+func WithBatchSize(batchSize int) Option {
+	return func(o *options) {
+		o.listener.BatchSize = batchSize
+	}
+}
+
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.listener.Logger = logger
+	}
+}
+
+func WithRedactKeys(keys ...string) Option {
+	return func(o *options) {
+		o.listener.RedactKeys = append(o.listener.RedactKeys, keys...)
+	}
+}
+
+// WithLagTracker records the lag in the tracker instead of the tracker of the listener,
+// e.g. to share one tracker between listeners. Metrics reports the lags of the tracker.
+func WithLagTracker(tracker *LagTracker) Option {
+	return func(o *options) {
+		o.listener.lag = tracker
+	}
+}
+
+// WithTimestamp extracts the server time of events for the time lag, e.g. DataTimestamp("timestamp").
+func WithTimestamp(timestamp TimestampFunc) Option {
+	return func(o *options) {
+		o.listener.Timestamp = timestamp
+	}
+}
+
+func WithNotificationHandler(handler func(*Notification)) Option {
+	return func(o *options) {
+		o.listener.OnNotification = handler
+	}
+}
+
+// WithStrictJSONRPC frames the requests and validates the responses as JSON-RPC 2.0, see EventListener.StrictJSONRPC.
+func WithStrictJSONRPC() Option {
+	return func(o *options) {
+		o.listener.StrictJSONRPC = true
+	}
+}
+
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.listener.Codec = codec
+	}
+}
+
+// WithCompression negotiates permessage-deflate with the level of compression of sent messages.
+func WithCompression(level int) Option {
+	return func(o *options) {
+		o.listener.EnableCompression = true
+		o.listener.CompressionLevel = level
+	}
+}
+
+// WithDialer connects with a copy of the dialer, e.g. for a proxy or handshake timeout.
+// The subprotocols and compression are still set by the listener.
+func WithDialer(dialer *websocket.Dialer) Option {
+	return func(o *options) {
+		o.listener.dialer = dialer
+	}
+}
+
+// WithEvents sends the received messages to the channel, which is closed by Close.
+func WithEvents(event chan<- *EventMessage) Option {
+	return func(o *options) {
+		o.listener.event = event
+	}
+}
+
+// WithSink attaches the sink, see AddSink.
+func WithSink(sink Sink) Option {
+	return func(o *options) {
+		o.sinks = append(o.sinks, sink)
+	}
+}
+
+// WithHandler calls the handler with every received message from a goroutine of its own, see AddSink.
+// Messages are shared with other receivers and must not be modified.
+func WithHandler(handler func(*EventMessage)) Option {
+	return WithSink(handlerSink(handler))
+}
+
+type handlerSink func(*EventMessage)
+
+func (h handlerSink) Write(_ context.Context, message *EventMessage) error {
+	h(message)
+	return nil
+}
+
+func (h handlerSink) Close() error {
+	return nil
+}
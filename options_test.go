@@ -0,0 +1,112 @@
+package eventlistener
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tracker := NewLagTracker()
+	listener := New(":8888",
+		WithName("monitor-1"),
+		WithToken("token"),
+		WithPongWait(10*time.Second),
+		WithResponseWait(time.Second),
+		WithReconnection(time.Second, 3),
+		WithCodec(CBORCodec),
+		WithCompression(5),
+		WithOversizeRecovery(32),
+		WithRedactKeys("seed"),
+		WithLagTracker(tracker),
+	)
+
+	assert.Equal(t, "monitor-1", listener.Name)
+	assert.Equal(t, "token", listener.Token)
+	assert.Equal(t, 10*time.Second, listener.PongWait)
+	assert.Equal(t, 9*time.Second, listener.PingPeriod)
+	assert.Equal(t, time.Second, listener.ResponseWait)
+	assert.Equal(t, writeWait, listener.WriteWait)
+	assert.Equal(t, time.Second, listener.ReconnectionDelay)
+	assert.Equal(t, 3, listener.ReconnectionAttempts)
+	assert.Equal(t, CBORCodec, listener.Codec)
+	assert.True(t, listener.EnableCompression)
+	assert.Equal(t, 5, listener.CompressionLevel)
+	assert.True(t, listener.OversizeRecovery)
+	assert.Equal(t, 32, listener.BatchSize)
+	assert.Equal(t, []string{"seed"}, listener.RedactKeys)
+	assert.Same(t, tracker, listener.lag)
+}
+
+func TestNew_handlers(t *testing.T) {
+	server := newTestServer(t)
+
+	var dials int32
+	dialer := *websocket.DefaultDialer
+	dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return new(net.Dialer).DialContext(ctx, network, addr)
+	}
+
+	events := make(chan *EventMessage, 10)
+	handled := make(chan *EventMessage, 10)
+	listener := New(server.addr(),
+		WithDialer(&dialer),
+		WithEvents(events),
+		WithHandler(func(message *EventMessage) { handled <- message }),
+	)
+
+	require.NoError(t, listener.ListenAndServe(context.Background()))
+	defer listener.Close()
+
+	server.publish(1, `{"a":1}`)
+	_, err := listener.Subscribe(1, offset)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(0), receiveEvent(t, events).Offset)
+	assert.Equal(t, uint64(0), receiveEvent(t, handled).Offset)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&dials))
+	assert.NotZero(t, listener.Metrics().WireBytesRead)
+}
+
+func TestEventListener_Apply(t *testing.T) {
+	server := newTestServer(t)
+	listener := NewEventListener(server.addr(), nil)
+
+	require.NoError(t, listener.Apply(WithToken("token")))
+	assert.Equal(t, "token", listener.Token)
+
+	require.NoError(t, listener.ListenAndServe(context.Background()))
+	defer listener.Close()
+
+	assert.Equal(t, ErrRunning, listener.Apply(WithToken("other"), WithSink(NewWriterSink(nil))))
+	assert.Equal(t, "token", listener.Token)
+	assert.Empty(t, listener.sinks)
+}
+
+func TestEventListener_ApplyConcurrent(t *testing.T) {
+	server := newTestServer(t)
+	listener := NewEventListener(server.addr(), nil)
+	defer listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan error, 1)
+	go func() { started <- listener.ListenAndServe(ctx) }()
+
+	// Either the options are applied before the start or not at all, never while the pumps read them
+	for {
+		err := listener.Apply(WithResponseWait(time.Second), WithPongWait(time.Minute), WithMaxInFlight(8))
+		if err == ErrRunning {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.NoError(t, <-started)
+}
@@ -2,8 +2,8 @@ package eventlistener
 
 import (
 	"context"
+	"fmt"
 	"github.com/gorilla/websocket"
-	"go.uber.org/zap"
 	"time"
 )
 
@@ -13,22 +13,23 @@ const (
 	msgParentContextDone = "parent context done"
 )
 
-func (e *EventListener) readPump(parentContext context.Context) error {
-	log := pumpsLog.Named("readPump")
+func (s *session) readPump(parentContext context.Context) error {
+	e := s.listener
+	log := e.logger().Named("readPump")
 
 	defer func() {
-		_ = e.conn.Close()
+		_ = s.conn.Close()
 		log.Info(msgPumpStopped)
 	}()
 
 	log.Info(msgPumpRunning)
 
-	e.conn.SetReadLimit(e.MessageSizeLimit)
-	err := e.conn.SetReadDeadline(time.Now().Add(e.PongWait))
+	s.conn.SetReadLimit(e.MessageSizeLimit)
+	err := s.conn.SetReadDeadline(time.Now().Add(e.PongWait))
 	if err != nil {
 		return err
 	}
-	e.conn.SetPongHandler(func(string) error { return e.conn.SetReadDeadline(time.Now().Add(e.PongWait)) })
+	s.conn.SetPongHandler(func(string) error { return s.conn.SetReadDeadline(time.Now().Add(e.PongWait)) })
 
 loop:
 	for {
@@ -37,86 +38,131 @@ loop:
 			log.Debug(msgParentContextDone)
 			break loop
 		default:
-			_, message, err := e.conn.ReadMessage()
+			_, message, err := s.conn.ReadMessage()
 			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					log.Error("conn.ReadMessage", zap.Error(err))
+				if err == websocket.ErrReadLimit {
+					err = fmt.Errorf("%w: limit %d bytes", ErrMessageTooLarge, e.MessageSizeLimit)
+					log.Error("conn.ReadMessage", "error", err)
+				} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+					log.Error("conn.ReadMessage", "error", err)
 				}
+				e.setErr(err)
 				return err
 			}
+			e.counters.received(len(message))
 
-			if err := e.processMessage(message); err != nil {
-				log.Error("processMessage", zap.Error(err))
-				if err := closeMessage(e.conn, e.WriteWait); err != nil {
-					log.Error("closeMessage", zap.Error(err))
+			responses, err := e.processMessage(s.codec, message)
+			if err != nil {
+				log.Error("processMessage", "error", err)
+				if err := closeMessage(s.conn, e.WriteWait); err != nil {
+					log.Error("closeMessage", "error", err)
 					return err
 				}
 				return err
 			}
+
+			for _, response := range responses {
+				select {
+				case s.response <- response:
+				case <-s.done:
+					log.Debug("response dropped, session closed")
+				}
+			}
 		}
 	}
 
 	return nil
 }
 
-func (e *EventListener) responsePump(ctx context.Context, send <-chan *responseQueue) {
-	log := pumpsLog.Named("responsePump")
-	process := make(map[string]chan *responseMessage)
+// responsePump hands the responses to the requests and fails the requests past their deadline.
+// Each finished request is reported on completed. It stops with the writePump which closes send.
+func (s *session) responsePump(send <-chan *responseQueue, completed chan<- struct{}) {
+	log := s.listener.logger().Named("responsePump")
+	process := make(map[string]*responseQueue)
 	defer func() {
-		for ID, ch := range process {
-			if ch != nil {
-				close(ch)
-			}
+		for ID, message := range process {
+			close(message.response)
 			delete(process, ID)
 		}
 
 		log.Info(msgPumpStopped)
 	}()
 
+	// Deadlines only grow, the timer is armed for the earliest one
+	var expire <-chan time.Time
+	arm := func() {
+		var next time.Time
+		for _, message := range process {
+			if next.IsZero() || message.deadline.Before(next) {
+				next = message.deadline
+			}
+		}
+		if next.IsZero() {
+			expire = nil
+		} else {
+			expire = time.After(time.Until(next))
+		}
+	}
+
 	log.Info(msgPumpRunning)
 	for {
 		select {
-		case <-ctx.Done():
-			log.Debug(msgParentContextDone)
-			return
 		case message, ok := <-send:
 			if !ok {
 				log.Debug("close send channel")
 				return
 			}
-			if message.response != nil { // Add wait response
-				process[message.ID] = message.response
+			process[message.ID] = message
+			if expire == nil {
+				arm()
 			}
 
-		case response, ok := <-e.response:
-			if !ok {
-				log.Debug("close response channel")
-				return
-			}
+		case response := <-s.response:
 			ID := *response.ID
-			if ch, ok := process[ID]; ok {
-				if ch != nil {
-					ch <- response
-					close(ch)
-				}
+			if message, ok := process[ID]; ok {
+				message.response <- response
+				close(message.response)
 				delete(process, ID)
+				completed <- struct{}{}
 			}
+
+		case now := <-expire:
+			for ID, message := range process {
+				if !now.Before(message.deadline) {
+					log.Error("request timeout", "id", ID)
+					message.fail(fmt.Errorf("request timeout: %s %s", message.request.Method, ID))
+					delete(process, ID)
+					completed <- struct{}{}
+				}
+			}
+			arm()
 		}
 	}
 }
 
-func (e *EventListener) writePump(parentContext context.Context) error {
-	log := pumpsLog.Named("writePump")
+// writePump writes the requests without waiting for their responses, up to MaxInFlight at a time.
+func (s *session) writePump(parentContext context.Context) error {
+	e := s.listener
+	log := e.logger().Named("writePump")
 
 	ticker := time.NewTicker(e.PingPeriod)
+	frameType := s.codec.FrameType()
 	waitResponse := make(chan *responseQueue)
 
-	go e.responsePump(parentContext, waitResponse)
+	limit := e.MaxInFlight
+	if limit < 1 {
+		limit = 1
+	}
+	inFlight := 0
+	completed := make(chan struct{}, limit)
+
+	go s.responsePump(waitResponse, completed)
 
 	defer func() {
+		close(s.done)
 		close(waitResponse)
 		ticker.Stop()
-		_ = e.conn.Close()
+		_ = s.conn.Close()
 
 		log.Info(msgPumpStopped)
 	}()
@@ -125,32 +171,47 @@ func (e *EventListener) writePump(parentContext context.Context) error {
 
 loop:
 	for {
+		// Requests wait in the queue while all slots are taken
+		send := e.send
+		if inFlight >= limit {
+			send = nil
+		}
+
 		select {
 		case <-parentContext.Done():
 			log.Debug(msgParentContextDone)
 			break loop
 
-		case message, ok := <-e.send:
-			if !ok {
-				// The session closed the channel.
-				log.Debug("close send channel")
-				if err := closeMessage(e.conn, e.WriteWait); err != nil {
-					log.Error("closeMessage", zap.Error(err))
-					return err
-				}
-				break loop
+		case <-completed:
+			inFlight--
+
+		case message := <-send:
+			if message.session != nil && message.session != s {
+				message.fail(ConnectionClosed)
+				continue
 			}
-			err := writeMessage(e.conn, e.WriteWait, message.message)
+			// Encoded here, the codec depends on the connection which takes the request
+			encoded, err := message.request.encode(s.codec, e.StrictJSONRPC)
 			if err != nil {
-				log.Error("writeMessage", zap.Error(err))
-				return err
+				log.Error("encode", "error", err)
+				message.fail(err)
+				continue
 			}
-			if message.response != nil {
-				waitResponse <- message
+			e.logger().Debug("sendRequest", "request", e.loggedFrame(s.codec, encoded))
+
+			// Wait for the response before writing, it may be read before writeMessage returns
+			message.deadline = time.Now().Add(e.ResponseWait)
+			waitResponse <- message
+			inFlight++
+
+			if err := writeMessage(s.conn, e.WriteWait, frameType, encoded); err != nil {
+				log.Error("writeMessage", "error", err)
+				return err
 			}
+			e.counters.sent(len(encoded))
 		case <-ticker.C:
-			if err := pingMessage(e.conn, e.WriteWait); err != nil {
-				log.Error("pingMessage", zap.Error(err))
+			if err := pingMessage(s.conn, e.WriteWait); err != nil {
+				log.Error("pingMessage", "error", err)
 				return err
 			}
 		}
@@ -0,0 +1,97 @@
+package eventlistener
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// subscribeAll subscribes to the topics concurrently.
+func subscribeAll(listener *EventListener, topics int) []error {
+	errs := make([]error, topics)
+
+	var wg sync.WaitGroup
+	for i := 0; i < topics; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = listener.Subscribe(EventType(i), 0)
+		}(i)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+func TestEventListener_pipelining(t *testing.T) {
+	server := newTestServer(t)
+	server.latency = 50 * time.Millisecond
+
+	startup := func(limit int) time.Duration {
+		listener := New(server.addr(), WithMaxInFlight(limit))
+		require.NoError(t, listener.ListenAndServe(context.Background()))
+		defer listener.Close()
+
+		start := time.Now()
+		for _, err := range subscribeAll(listener, 10) {
+			require.NoError(t, err)
+		}
+		return time.Since(start)
+	}
+
+	// 10 round trips one by one, or all of them at once
+	serial := startup(1)
+	assert.True(t, serial >= 10*server.latency, serial)
+	pipelined := startup(10)
+	assert.True(t, pipelined < 5*server.latency, pipelined)
+}
+
+func TestEventListener_requestTimeout(t *testing.T) {
+	server := newTestServer(t)
+	server.silent = true
+
+	listener := New(server.addr(), WithToken("secret-token"), WithMaxInFlight(2), WithResponseWait(50*time.Millisecond))
+	require.NoError(t, listener.ListenAndServe(context.Background()))
+	defer listener.Close()
+
+	start := time.Now()
+	errs := subscribeAll(listener, 3)
+	elapsed := time.Since(start)
+
+	for _, err := range errs {
+		require.Error(t, err)
+		assert.True(t, strings.HasPrefix(err.Error(), "request timeout: subscribe "), err)
+		assert.NotContains(t, err.Error(), "secret-token")
+	}
+	// The third request is written once a slot is freed by a timeout
+	assert.True(t, elapsed >= 100*time.Millisecond, elapsed)
+	assert.Equal(t, 3, server.count(methodSubscribe))
+}
+
+// BenchmarkSubscribe measures the startup time of subscribing to 100 topics over a link with 1ms latency.
+func BenchmarkSubscribe(b *testing.B) {
+	server := newTestServer(b)
+	server.latency = time.Millisecond
+
+	for _, limit := range []int{1, 10, maxInFlight} {
+		b.Run(fmt.Sprintf("max=%d", limit), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				listener := New(server.addr(), WithMaxInFlight(limit), WithLogger(NopLogger()))
+				if err := listener.ListenAndServe(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+				for _, err := range subscribeAll(listener, 100) {
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+				listener.Close()
+			}
+		})
+	}
+}
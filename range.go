@@ -0,0 +1,118 @@
+package eventlistener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrIncompleteRange = errors.New("range incomplete: no events within the idle wait")
+
+// ReadRange reads the events of eventType with offsets from from to to inclusive.
+// It uses a separate connection, so live subscriptions of the listener are not affected.
+// The range is capped at the last event before the head offset of the server, a range past
+// the head is empty. Reading stops when the last event of the range is received. If no events
+// arrive within RangeIdleWait before, the events read so far are returned with ErrIncompleteRange.
+func (e *EventListener) ReadRange(parentContext context.Context, eventType EventType, from, to uint64) ([]*Event, error) {
+	if from > to {
+		return nil, fmt.Errorf("invalid range: %d > %d", from, to)
+	}
+
+	result := make([]*Event, 0)
+	ctx, cancel := context.WithCancel(parentContext)
+	defer cancel()
+
+	reader := e.newRangeReader()
+	if err := reader.ListenAndServe(ctx); err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	heads, err := reader.HeadOffsets(ctx, eventType)
+	if err != nil {
+		return nil, err
+	}
+	head := heads[eventType]
+	if head <= from {
+		return result, nil
+	}
+	if to > head-1 {
+		to = head - 1
+	}
+
+	events := make(chan *EventMessage)
+	consumer := reader.NewConsumer(events)
+	done := func(err error) ([]*Event, error) {
+		if err := consumer.Close(); err != nil {
+			e.logger().Named("readRange").Debug("unsubscribe error", "error", err)
+		}
+		return result, err
+	}
+
+	ok, err := consumer.Subscribe(eventType, from)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("subscribe %s rejected", eventType.ToString())
+	}
+
+	idle := time.NewTimer(e.RangeIdleWait)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-idle.C:
+			return done(ErrIncompleteRange)
+		case message, ok := <-events:
+			if !ok {
+				return result, ListenerClosed
+			}
+
+			for _, event := range message.Events {
+				if event.Offset < from {
+					continue
+				}
+				if event.Offset > to {
+					return done(nil)
+				}
+
+				result = append(result, event)
+				if event.Offset == to {
+					return done(nil)
+				}
+			}
+
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(e.RangeIdleWait)
+		}
+	}
+}
+
+// newRangeReader creates a listener for a historical read with the same settings.
+func (e *EventListener) newRangeReader() *EventListener {
+	reader := NewEventListener(e.Addr, nil)
+	reader.Name = e.Name + "/range"
+	reader.Logger = e.Logger
+	reader.Token = e.Token
+	reader.RedactKeys = e.RedactKeys
+	reader.Timestamp = e.Timestamp
+	reader.Codec = e.Codec
+	reader.StrictJSONRPC = e.StrictJSONRPC
+	reader.EnableCompression = e.EnableCompression
+	reader.CompressionLevel = e.CompressionLevel
+	reader.dialer = e.dialer
+	reader.MessageSizeLimit = e.MessageSizeLimit
+	reader.BatchSize = e.batchSizeHint()
+	reader.WriteWait = e.WriteWait
+	reader.PongWait = e.PongWait
+	reader.PingPeriod = e.PingPeriod
+	reader.ResponseWait = e.ResponseWait
+	reader.MaxInFlight = e.MaxInFlight
+	return reader
+}
@@ -0,0 +1,107 @@
+package eventlistener
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEventListener_ReadRange(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+	listener.RangeIdleWait = 100 * time.Millisecond
+
+	for i := 0; i < 5; i++ {
+		server.publish(1, `{}`)
+	}
+
+	events, err := listener.ReadRange(context.Background(), 1, 1, 3)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, uint64(1), events[0].Offset)
+	assert.Equal(t, uint64(3), events[2].Offset)
+
+	// Stops at the head of the topic
+	events, err = listener.ReadRange(context.Background(), 1, 3, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(4), events[1].Offset)
+
+	assert.Equal(t, 2, server.count(methodUnsubscribe))
+
+	listener.Lock()
+	assert.Empty(t, listener.subscriptions)
+	listener.Unlock()
+}
+
+func TestEventListener_ReadRangeHead(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+	listener.RangeIdleWait = time.Minute
+
+	for i := 0; i < 3; i++ {
+		server.publish(1, `{}`)
+	}
+
+	// Capped at the head, without waiting for events past it
+	events, err := listener.ReadRange(context.Background(), 1, 1, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(2), events[1].Offset)
+
+	events, err = listener.ReadRange(context.Background(), 1, 3, 100)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestEventListener_ReadRangeIncomplete(t *testing.T) {
+	server := newTestServer(t)
+	server.ahead = 2
+	listener := newTestListener(t, server, nil)
+	listener.RangeIdleWait = 100 * time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		server.publish(1, `{}`)
+	}
+
+	events, err := listener.ReadRange(context.Background(), 1, 1, 100)
+	assert.True(t, errors.Is(err, ErrIncompleteRange))
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(2), events[1].Offset)
+}
+
+func TestEventListener_ReadRangeInvalid(t *testing.T) {
+	listener := NewEventListener(":1234", nil)
+
+	_, err := listener.ReadRange(context.Background(), 1, 2, 1)
+	require.Error(t, err)
+}
+
+func TestEventListener_ReadRangeCanceled(t *testing.T) {
+	server := newTestServer(t)
+	server.ahead = 10 // events which never arrive
+	listener := NewEventListener(server.addr(), nil)
+	listener.RangeIdleWait = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := listener.ReadRange(ctx, 1, 0, 10)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestEventListener_newRangeReader(t *testing.T) {
+	logger := NopLogger()
+	listener := New(":8888", WithName("monitor-1"), WithLogger(logger), WithToken("token"), WithRedactKeys("seed"),
+		WithTimestamp(DataTimestamp("timestamp")))
+
+	reader := listener.newRangeReader()
+	assert.Equal(t, "monitor-1/range", reader.Name)
+	assert.Equal(t, logger, reader.Logger)
+	assert.Equal(t, "token", reader.Token)
+	assert.Equal(t, []string{"seed"}, reader.RedactKeys)
+	assert.NotNil(t, reader.Timestamp)
+}
@@ -2,41 +2,42 @@ package eventlistener
 
 import (
 	"context"
-	"github.com/gorilla/websocket"
-	"go.uber.org/zap"
+	"errors"
 	"golang.org/x/sync/errgroup"
-	"net/url"
 	"time"
 )
 
 // Run starts the action listener tries to reconnect and restore subscriptions in case of an error.
 // Run in goroutine because this method is blocking
 func (e *EventListener) Run(parentContext context.Context) {
-	log := pumpsLog.Named("reconnect")
+	log := e.logger().Named("reconnect")
+	if err := e.start(); err != nil {
+		log.Error("start", "error", err)
+		return
+	}
+
 	defer func() {
 		e.Close()
 		log.Debug("listener close")
 	}()
 
-	u := url.URL{Scheme: "ws", Host: e.Addr, Path: "/"}
 	attempt := 1
 
 	for {
-		log.Debug("connection", zap.Int("attempt", attempt))
+		log.Debug("connection", "attempt", attempt)
 
 		g, ctx := errgroup.WithContext(parentContext)
-		var err error
 
-		e.conn, _, err = websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+		s, err := e.connect(ctx)
 		if err == nil {
 			attempt = 0
 
-			log.Debug("connected", zap.String("url", u.String()))
+			log.Debug("connected", "url", e.url())
 			g.Go(func() error {
-				return e.readPump(ctx)
+				return s.readPump(ctx)
 			})
 			g.Go(func() error {
-				return e.writePump(ctx)
+				return s.writePump(ctx)
 			})
 
 			// Group by offset
@@ -53,18 +54,28 @@ func (e *EventListener) Run(parentContext context.Context) {
 			e.Unlock()
 
 			for offset, eventTypes := range subscriptions {
-				if _, err := e.BatchSubscribe(eventTypes, offset); err != nil {
-					log.Error("batchSubscribe error", zap.Error(err))
+				if _, err := e.batchSubscribe(s, eventTypes, offset); err != nil {
+					log.Error("batchSubscribe error", "error", err)
 					break
 				}
 			}
 
 			err = g.Wait()
 			if err != nil {
-				log.Error("wait error", zap.Error(err))
+				log.Error("wait error", "error", err)
+			}
+
+			// The same frame would be sent again, retry only with fewer events per frame
+			if errors.Is(err, ErrMessageTooLarge) {
+				batchSize, ok := e.lowerBatchSize()
+				if !ok {
+					log.Error("message too large, stop reconnecting", "limit", e.MessageSizeLimit)
+					return
+				}
+				log.Info("message too large, lower batch size", "batchSize", batchSize)
 			}
 		} else {
-			log.Error("connection error", zap.String("url", u.String()), zap.Error(err))
+			log.Error("connection error", "url", e.url(), "error", err)
 		}
 
 		attempt++
@@ -80,3 +91,31 @@ func (e *EventListener) Run(parentContext context.Context) {
 		}
 	}
 }
+
+// lowerBatchSize halves the batch size hint for the oversize recovery.
+// Returns false if the recovery is disabled or a frame of one event is already too large.
+func (e *EventListener) lowerBatchSize() (int, bool) {
+	if !e.OversizeRecovery {
+		return 0, false
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	batchSize := e.batchSize
+	if batchSize == 0 {
+		batchSize = e.BatchSize
+	}
+
+	switch {
+	case batchSize == 1:
+		return 0, false
+	case batchSize == 0:
+		batchSize = recoveryBatchSize
+	default:
+		batchSize /= 2
+	}
+
+	e.batchSize = batchSize
+	return batchSize, true
+}
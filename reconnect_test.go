@@ -2,8 +2,13 @@ package eventlistener
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEventListener_reconnectError(t *testing.T) {
@@ -21,3 +26,120 @@ func TestEventListener_reconnectError(t *testing.T) {
 	assert.Equal(t, ListenerClosed, err)
 	assert.False(t, ok)
 }
+
+func TestEventListener_oversizeRecovery(t *testing.T) {
+	server := newTestServer(t)
+	data := fmt.Sprintf("%q", strings.Repeat("x", 500))
+	for i := 0; i < 5; i++ {
+		server.publish(1, data)
+	}
+
+	parentContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan *EventMessage)
+	listener := NewEventListener(server.addr(), events)
+	listener.MessageSizeLimit = 1500
+	listener.ReconnectionDelay = 10 * time.Millisecond
+	listener.OversizeRecovery = true
+	go listener.Run(parentContext)
+
+	ok, err := listener.Subscribe(1, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var offsets []uint64
+	for len(offsets) < 5 {
+		select {
+		case message := <-events:
+			assert.LessOrEqual(t, len(message.Events), 2)
+			for _, event := range message.Events {
+				offsets = append(offsets, event.Offset)
+			}
+		case <-time.After(waitEventsTimeout):
+			t.Fatal("no events")
+		}
+	}
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4}, offsets)
+	assert.Equal(t, 2, listener.batchSizeHint())
+	assert.True(t, errors.Is(listener.Err(), ErrMessageTooLarge))
+}
+
+func TestEventListener_oversizeStop(t *testing.T) {
+	server := newTestServer(t)
+	server.publish(1, fmt.Sprintf("%q", strings.Repeat("x", 2000)))
+
+	parentContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan *EventMessage)
+	listener := NewEventListener(server.addr(), events)
+	listener.MessageSizeLimit = 1500
+	listener.ReconnectionDelay = 10 * time.Millisecond
+	listener.OversizeRecovery = true
+	listener.BatchSize = 1
+	go listener.Run(parentContext)
+
+	ok, err := listener.Subscribe(1, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(waitEventsTimeout):
+		t.Fatal("listener not closed")
+	}
+	assert.True(t, errors.Is(listener.Err(), ErrMessageTooLarge))
+	assert.Equal(t, 1, server.count(methodSubscribe))
+}
+
+func TestEventListener_flappingServer(t *testing.T) {
+	server := newTestServer(t)
+
+	parentContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan *EventMessage, 100)
+	listener := NewEventListener(server.addr(), events)
+	listener.ReconnectionDelay = time.Millisecond
+	listener.ReconnectionAttempts = 100
+	go listener.Run(parentContext)
+
+	ok, err := listener.Subscribe(1, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, ErrRunning, listener.ListenAndServe(parentContext))
+
+	// Requests sent while connections come and go fail or succeed, but never race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_, _ = listener.Subscribe(2, 0)
+		}
+	}()
+
+	const count = 10
+	for i := 0; i < count; i++ {
+		server.publish(1, `{}`)
+		server.dropClients()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var offsets []uint64
+	for len(offsets) < count {
+		select {
+		case message := <-events:
+			for _, event := range message.Events {
+				if event.EventType == 1 {
+					offsets = append(offsets, event.Offset)
+				}
+			}
+		case <-time.After(waitEventsTimeout):
+			t.Fatal("no events")
+		}
+	}
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, offsets)
+	<-done
+}
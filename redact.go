@@ -0,0 +1,88 @@
+package eventlistener
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+)
+
+const redacted = "[REDACTED]"
+
+// defaultRedactKeys are the keys of JSON objects whose values are never logged.
+var defaultRedactKeys = []string{"token"}
+
+// loggedFrame logs a frame as JSON with the secrets redacted at any depth, including Event.Data.
+// Binary frames are transcoded to JSON. The frame is encoded only if the line is written.
+type loggedFrame struct {
+	codec   Codec
+	message []byte
+	keys    map[string]struct{}
+}
+
+func (f loggedFrame) MarshalJSON() ([]byte, error) {
+	message := f.message
+	if f.codec.FrameType() != websocket.TextMessage {
+		var err error
+		if message, err = transcode(f.codec, message); err != nil {
+			return nil, err
+		}
+	}
+	return redactJSON(message, f.keys)
+}
+
+func (f loggedFrame) String() string {
+	message, err := f.MarshalJSON()
+	if err != nil {
+		return err.Error()
+	}
+	return string(message)
+}
+
+// redactKeys returns the redacted keys of the listener, the defaults and EventListener.RedactKeys.
+// Must be called with the lock held.
+func (e *EventListener) redactKeys() map[string]struct{} {
+	keys := make(map[string]struct{}, len(defaultRedactKeys)+len(e.RedactKeys))
+	for _, key := range defaultRedactKeys {
+		keys[key] = struct{}{}
+	}
+	for _, key := range e.RedactKeys {
+		keys[key] = struct{}{}
+	}
+	return keys
+}
+
+// loggedFrame wraps a frame for logging.
+func (e *EventListener) loggedFrame(codec Codec, message []byte) loggedFrame {
+	_, keys := e.logging()
+	return loggedFrame{codec: codec, message: message, keys: keys}
+}
+
+// redactJSON replaces the values of the keys at any depth.
+func redactJSON(message []byte, keys map[string]struct{}) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactValue(value, keys))
+}
+
+func redactValue(value interface{}, keys map[string]struct{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if _, ok := keys[key]; ok {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(item, keys)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item, keys)
+		}
+	}
+	return value
+}
@@ -0,0 +1,158 @@
+package eventlistener
+
+import "errors"
+
+var errInvalidFrame = errors.New("invalid frame")
+
+// frameMembers are the raw top-level members of a JSON frame.
+type frameMembers struct {
+	jsonrpc []byte
+	id      []byte
+	result  []byte
+	error   []byte
+	method  []byte
+	params  []byte
+}
+
+// scanFrame finds the top-level members of a JSON frame without decoding them,
+// so the result is decoded only once and straight into its final type.
+// The values are validated when they are decoded.
+func scanFrame(data []byte) (frameMembers, error) {
+	var members frameMembers
+
+	i := skipSpace(data, 0)
+	if i >= len(data) || data[i] != '{' {
+		return members, errInvalidFrame
+	}
+
+	i = skipSpace(data, i+1)
+	if i < len(data) && data[i] == '}' {
+		return members, nil
+	}
+
+	for {
+		if i >= len(data) || data[i] != '"' {
+			return members, errInvalidFrame
+		}
+
+		end, err := skipString(data, i)
+		if err != nil {
+			return members, err
+		}
+		key := data[i+1 : end-1]
+
+		i = skipSpace(data, end)
+		if i >= len(data) || data[i] != ':' {
+			return members, errInvalidFrame
+		}
+
+		start := skipSpace(data, i+1)
+		if i, err = skipValue(data, start); err != nil {
+			return members, err
+		}
+
+		switch string(key) {
+		case "jsonrpc":
+			members.jsonrpc = data[start:i]
+		case "id":
+			members.id = data[start:i]
+		case "result":
+			members.result = data[start:i]
+		case "error":
+			members.error = data[start:i]
+		case "method":
+			members.method = data[start:i]
+		case "params":
+			members.params = data[start:i]
+		}
+
+		i = skipSpace(data, i)
+		if i >= len(data) {
+			return members, errInvalidFrame
+		}
+
+		switch data[i] {
+		case ',':
+			i = skipSpace(data, i+1)
+		case '}':
+			return members, nil
+		default:
+			return members, errInvalidFrame
+		}
+	}
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// skipString returns the position after the string starting at i.
+func skipString(data []byte, i int) (int, error) {
+	for j := i + 1; j < len(data); j++ {
+		switch data[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		}
+	}
+	return 0, errInvalidFrame
+}
+
+// skipValue returns the position after the value starting at i.
+func skipValue(data []byte, i int) (int, error) {
+	if i >= len(data) {
+		return 0, errInvalidFrame
+	}
+
+	switch data[i] {
+	case '"':
+		return skipString(data, i)
+	case '{', '[':
+		depth := 0
+		for i < len(data) {
+			switch data[i] {
+			case '"':
+				end, err := skipString(data, i)
+				if err != nil {
+					return 0, err
+				}
+				i = end
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+			i++
+		}
+		return 0, errInvalidFrame
+	default:
+		start := i
+		for i < len(data) {
+			switch data[i] {
+			case ',', '}', ']', ' ', '\t', '\r', '\n':
+				if i == start {
+					return 0, errInvalidFrame
+				}
+				return i, nil
+			}
+			i++
+		}
+		if i == start {
+			return 0, errInvalidFrame
+		}
+		return i, nil
+	}
+}
@@ -0,0 +1,92 @@
+package eventlistener
+
+import "errors"
+
+var NotSubscribed = errors.New("not subscribed")
+
+// Seek moves the subscription of eventType to offset, e.g. to replay a topic from history while live.
+// It unsubscribes and subscribes again at offset on the same connection. Events of eventType
+// which were sent by the server before the new subscription are discarded, so after Seek returns
+// the stream continues from offset. The change applies to all consumers of eventType.
+func (e *EventListener) Seek(eventType EventType, offset uint64) (bool, error) {
+	e.consumerLock.Lock()
+	defer e.consumerLock.Unlock()
+
+	e.Lock()
+	_, subscribed := e.subscriptions[eventType]
+	if subscribed {
+		e.seeking[eventType] = ""
+	}
+	e.Unlock()
+
+	if !subscribed {
+		return false, NotSubscribed
+	}
+
+	// The subscription stays tracked until the new one returns, and is restored if it fails,
+	// so a reconnection in between subscribes again
+	ok, err := e.unsubscribe(eventType)
+	if err != nil || !ok {
+		e.stopSeeking(eventType)
+		return ok, err
+	}
+
+	request := e.newSubscribeMessage(eventType, offset)
+
+	e.Lock()
+	e.seeking[eventType] = request.ID
+	e.Unlock()
+
+	ok, err = e.subscribe(request, eventType, offset)
+	if err != nil || !ok {
+		e.stopSeeking(eventType)
+	}
+
+	return ok, err
+}
+
+func (e *EventListener) stopSeeking(eventType EventType) {
+	e.Lock()
+	delete(e.seeking, eventType)
+	e.Unlock()
+}
+
+// seekResponse ends seeking of the event type subscribed by the request ID.
+// Responses are processed in order with events, so every following event is fresh.
+func (e *EventListener) seekResponse(ID string) {
+	e.Lock()
+	defer e.Unlock()
+
+	for eventType, requestID := range e.seeking {
+		if requestID == ID {
+			delete(e.seeking, eventType)
+			return
+		}
+	}
+}
+
+// dropStale removes events of the event types being seeked.
+// Returns false if no events are left.
+func (e *EventListener) dropStale(message *EventMessage) bool {
+	e.Lock()
+	defer e.Unlock()
+
+	if len(e.seeking) == 0 || len(message.Events) == 0 {
+		return true
+	}
+
+	events := message.Events[:0]
+	for _, event := range message.Events {
+		if _, ok := e.seeking[event.EventType]; !ok {
+			events = append(events, event)
+		}
+	}
+
+	message.Events = events
+	if len(events) == 0 {
+		return false
+	}
+
+	message.Offset = events[len(events)-1].Offset
+	return true
+}
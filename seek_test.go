@@ -0,0 +1,97 @@
+package eventlistener
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEventListener_Seek(t *testing.T) {
+	server := newTestServer(t)
+	events := make(chan *EventMessage, 10)
+	listener := newTestListener(t, server, events)
+
+	for i := 0; i < 3; i++ {
+		server.publish(1, `{}`)
+	}
+
+	ok, err := listener.Subscribe(1, 2)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), receiveEvent(t, events).Offset)
+
+	ok, err = listener.Seek(1, 0)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	message := <-events
+	require.Len(t, message.Events, 3)
+	assert.Equal(t, uint64(0), message.Events[0].Offset)
+
+	assert.Eventually(t, func() bool {
+		listener.Lock()
+		defer listener.Unlock()
+		return listener.subscriptions[1] == 3
+	}, waitEventsTimeout, time.Millisecond)
+}
+
+func TestEventListener_SeekFailed(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+
+	ok, err := listener.Subscribe(1, 2)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	server.Lock()
+	server.refuse = true
+	server.Unlock()
+
+	// The subscription stays tracked, so a reconnection subscribes again from the previous offset
+	_, err = listener.Seek(1, 0)
+	assert.EqualError(t, err, "subscription refused")
+
+	listener.Lock()
+	tracked, subscribed := listener.subscriptions[1]
+	_, seeking := listener.seeking[1]
+	listener.Unlock()
+	assert.True(t, subscribed)
+	assert.Equal(t, uint64(2), tracked)
+	assert.False(t, seeking)
+}
+
+func TestEventListener_SeekNotSubscribed(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+
+	ok, err := listener.Seek(1, 0)
+	assert.Equal(t, NotSubscribed, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, server.count(methodUnsubscribe))
+}
+
+func TestEventListener_dropStale(t *testing.T) {
+	events := make(chan *EventMessage, 1)
+	listener := NewEventListener("", events)
+	listener.subscriptions[1] = 5
+	listener.subscriptions[2] = 5
+	listener.seeking[1] = "ID"
+
+	message := []byte(`{"result":{"offset":7,"events":[{"offset":6,"event_type":2},{"offset":7,"event_type":1}]}}`)
+	_, err := listener.processMessage(JSONCodec, message)
+	require.NoError(t, err)
+
+	received := <-events
+	require.Len(t, received.Events, 1)
+	assert.Equal(t, EventType(2), received.Events[0].EventType)
+	assert.Equal(t, uint64(6), received.Offset)
+	assert.Equal(t, uint64(5), listener.subscriptions[1])
+
+	// Only stale events are dropped without delivery
+	message = []byte(`{"result":{"offset":8,"events":[{"offset":8,"event_type":1}]}}`)
+	_, err = listener.processMessage(JSONCodec, message)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, uint64(5), listener.subscriptions[1])
+}
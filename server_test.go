@@ -0,0 +1,416 @@
+package eventlistener
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer is a minimal stand-in for the action monitor websocket service.
+type testServer struct {
+	*httptest.Server
+
+	sync.Mutex
+	events   map[string][]*Event
+	clients  map[*testClient]struct{}
+	requests map[string]int
+	codecs   []Codec // codecs accepted as subprotocols
+
+	compression bool          // negotiate permessage-deflate
+	muted       bool          // publish without pushing to clients, like a stalled stream
+	silent      bool          // never respond to requests
+	latency     time.Duration // delay of responses, requests are then handled concurrently like on a remote server
+	timeIndex   bool          // support getOffsetByTime, events are timed by the "timestamp" field of their data
+	strict      bool          // JSON-RPC 2.0, requests without the version are rejected and responses carry it
+	refuse      bool          // subscriptions fail with an error
+	ahead       uint64        // head offsets count events not replayed yet, like a lagging replica
+}
+
+type testClient struct {
+	sync.Mutex
+	conn   *websocket.Conn
+	codec  Codec
+	topics map[string]struct{}
+	strict bool
+}
+
+type testRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      string          `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type testParams struct {
+	Token     string    `json:"token"`
+	Topic     string    `json:"topic"`
+	Topics    []string  `json:"topics"`
+	Offset    uint64    `json:"offset"`
+	BatchSize int       `json:"batch_size"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func newTestServer(t testing.TB) *testServer {
+	s := &testServer{
+		events:   make(map[string][]*Event),
+		clients:  make(map[*testClient]struct{}),
+		requests: make(map[string]int),
+		codecs:   []Codec{MsgpackCodec, CBORCodec},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveWS))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) addr() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// count returns how many requests with the method the server has received.
+func (s *testServer) count(method string) int {
+	s.Lock()
+	defer s.Unlock()
+	return s.requests[method]
+}
+
+// publish appends an event to the topic and pushes it to the subscribed clients.
+func (s *testServer) publish(eventType EventType, data string) *Event {
+	topic := eventType.ToString()
+
+	s.Lock()
+	event := &Event{
+		Offset:    uint64(len(s.events[topic])),
+		EventType: eventType,
+		Data:      json.RawMessage(data),
+	}
+	s.events[topic] = append(s.events[topic], event)
+
+	clients := make([]*testClient, 0, len(s.clients))
+	for c := range s.clients {
+		if !s.muted {
+			clients = append(clients, c)
+		}
+	}
+	s.Unlock()
+
+	for _, c := range clients {
+		c.Lock()
+		if _, ok := c.topics[topic]; ok {
+			_ = c.writeEvents([]*Event{event})
+		}
+		c.Unlock()
+	}
+
+	return event
+}
+
+// notify pushes a notification to every client. A revocation also unsubscribes the clients from the topic.
+func (s *testServer) notify(method string, params interface{}) {
+	s.Lock()
+	clients := make([]*testClient, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.Unlock()
+
+	for _, c := range clients {
+		c.Lock()
+		if revocation, ok := params.(map[string]string); ok && method == NotificationSubscriptionRevoked {
+			delete(c.topics, revocation["topic"])
+		}
+		_ = c.write(struct {
+			Method string      `json:"method"`
+			Params interface{} `json:"params"`
+		}{method, params})
+		c.Unlock()
+	}
+}
+
+// push writes the frame to every client, e.g. a frame the listener doesn't know.
+func (s *testServer) push(frame interface{}) {
+	s.Lock()
+	clients := make([]*testClient, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.Unlock()
+
+	for _, c := range clients {
+		c.Lock()
+		_ = c.write(frame)
+		c.Unlock()
+	}
+}
+
+// dropClients closes every client connection.
+func (s *testServer) dropClients() {
+	s.Lock()
+	defer s.Unlock()
+	for c := range s.clients {
+		_ = c.conn.Close()
+	}
+}
+
+func (s *testServer) serveWS(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	upgrader := websocket.Upgrader{EnableCompression: s.compression}
+	for _, codec := range s.codecs {
+		upgrader.Subprotocols = append(upgrader.Subprotocols, codec.Subprotocol())
+	}
+	s.Unlock()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &testClient{conn: conn, codec: JSONCodec, topics: make(map[string]struct{})}
+	for _, codec := range s.codecs {
+		if codec.Subprotocol() == conn.Subprotocol() {
+			c.codec = codec
+		}
+	}
+	s.Lock()
+	s.clients[c] = struct{}{}
+	s.Unlock()
+
+	defer func() {
+		s.Lock()
+		delete(s.clients, c)
+		s.Unlock()
+		_ = conn.Close()
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if c.codec != JSONCodec {
+			if message, err = transcode(c.codec, message); err != nil {
+				return
+			}
+		}
+
+		request := new(testRequest)
+		if err := json.Unmarshal(message, request); err != nil {
+			return
+		}
+
+		s.Lock()
+		s.requests[request.Method]++
+		silent, latency, strict := s.silent, s.latency, s.strict
+		s.Unlock()
+
+		c.Lock()
+		c.strict = strict
+		c.Unlock()
+
+		switch {
+		case silent:
+		case latency > 0:
+			go func() {
+				time.Sleep(latency)
+				s.handle(c, request)
+			}()
+		default:
+			s.handle(c, request)
+		}
+	}
+}
+
+// handle responds to the request and pushes the backlog of subscribed topics.
+func (s *testServer) handle(c *testClient, request *testRequest) {
+	params := new(testParams)
+	_ = json.Unmarshal(request.Params, params)
+	if params.Topic != "" {
+		params.Topics = append(params.Topics, params.Topic)
+	}
+
+	c.Lock()
+	if c.strict && request.JSONRPC != jsonrpcVersion {
+		_ = c.writeError(request.ID, -32600, "invalid request")
+		c.Unlock()
+		return
+	}
+
+	switch request.Method {
+	case methodSubscribe, methodBatchSubscribe:
+		s.Lock()
+		refuse := s.refuse
+		s.Unlock()
+		if refuse {
+			_ = c.writeError(request.ID, -1, "subscription refused")
+			break
+		}
+		_ = c.writeResult(request.ID, true)
+		for _, topic := range params.Topics {
+			c.topics[topic] = struct{}{}
+			events := s.history(topic, params.Offset)
+			for len(events) > 0 {
+				batch := events
+				if params.BatchSize > 0 && len(batch) > params.BatchSize {
+					batch = batch[:params.BatchSize]
+				}
+				_ = c.writeEvents(batch)
+				events = events[len(batch):]
+			}
+		}
+	case methodUnsubscribe, methodBatchUnsubscribe:
+		ok := true
+		for _, topic := range params.Topics {
+			if _, subscribed := c.topics[topic]; !subscribed {
+				ok = false
+			}
+		}
+		if !ok {
+			_ = c.writeError(request.ID, -1, "topic not subscribed")
+			break
+		}
+		for _, topic := range params.Topics {
+			delete(c.topics, topic)
+		}
+		_ = c.writeResult(request.ID, true)
+	case methodListTopics:
+		s.Lock()
+		topics := make([]string, 0, len(s.events))
+		for topic := range s.events {
+			topics = append(topics, topic)
+		}
+		s.Unlock()
+		_ = c.writeResult(request.ID, topics)
+	case methodGetHeadOffsets:
+		s.Lock()
+		heads := make(map[string]uint64, len(params.Topics))
+		for _, topic := range params.Topics {
+			heads[topic] = uint64(len(s.events[topic])) + s.ahead
+		}
+		s.Unlock()
+		_ = c.writeResult(request.ID, heads)
+	case methodGetOffsetByTime:
+		s.Lock()
+		supported := s.timeIndex
+		events := s.events[params.Topic]
+		s.Unlock()
+		if !supported {
+			_ = c.writeError(request.ID, CodeMethodNotFound, "method not found")
+			break
+		}
+		offset := uint64(len(events))
+		for _, event := range events {
+			if timestamp, _ := DataTimestamp("timestamp")(event); !timestamp.Before(params.Timestamp) {
+				offset = event.Offset
+				break
+			}
+		}
+		_ = c.writeResult(request.ID, offset)
+	case "echo":
+		_ = c.writeResult(request.ID, request.Params)
+	default:
+		_ = c.writeError(request.ID, CodeMethodNotFound, "method not found")
+	}
+	c.Unlock()
+}
+
+func (s *testServer) history(topic string, offset uint64) []*Event {
+	s.Lock()
+	defer s.Unlock()
+
+	events := s.events[topic]
+	if offset >= uint64(len(events)) {
+		return nil
+	}
+	return append([]*Event(nil), events[offset:]...)
+}
+
+func (c *testClient) writeResult(ID string, result interface{}) error {
+	return c.write(struct {
+		JSONRPC string      `json:"jsonrpc,omitempty"`
+		ID      string      `json:"id"`
+		Result  interface{} `json:"result"`
+	}{c.version(), ID, result})
+}
+
+func (c *testClient) writeError(ID string, code int, message string) error {
+	return c.write(struct {
+		JSONRPC string    `json:"jsonrpc,omitempty"`
+		ID      string    `json:"id"`
+		Error   *RPCError `json:"error"`
+	}{c.version(), ID, &RPCError{Code: code, Message: message}})
+}
+
+// version is the jsonrpc member of the responses, only sent in strict mode.
+func (c *testClient) version() string {
+	if c.strict {
+		return jsonrpcVersion
+	}
+	return ""
+}
+
+func (c *testClient) writeEvents(events []*Event) error {
+	return c.write(struct {
+		Result *EventMessage `json:"result"`
+	}{&EventMessage{Offset: events[len(events)-1].Offset, Events: events}})
+}
+
+// write sends the frame encoded with the codec of the client.
+func (c *testClient) write(frame interface{}) error {
+	if c.codec == JSONCodec {
+		return c.conn.WriteJSON(frame)
+	}
+
+	message, err := encodeFrame(c.codec, frame)
+	if err != nil {
+		return err
+	}
+	return c.conn.WriteMessage(c.codec.FrameType(), message)
+}
+
+// encodeFrame encodes a JSON-shaped frame with a binary codec, like a server written in another language would.
+func encodeFrame(codec Codec, frame interface{}) ([]byte, error) {
+	message, err := json.Marshal(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return codec.Marshal(plain(value))
+}
+
+// plain replaces JSON numbers with integers where possible.
+func plain(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = plain(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = plain(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,38 @@
+package eventlistener
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+)
+
+// session is a connection of the listener with its own pumps and channels.
+// Every connection gets a new session, so the pumps of a closed connection never touch the next one.
+// Requests are queued on the listener and written by the session connected at the time,
+// unless they are bound to a session, e.g. the subscriptions restored by Run.
+type session struct {
+	listener *EventListener
+	conn     *websocket.Conn
+	codec    Codec // negotiated on the connection
+	response chan *responseMessage
+	done     chan struct{} // closed when the writePump stops, requests are no longer taken
+}
+
+// connect dials the server and creates a session on the connection.
+func (e *EventListener) connect(ctx context.Context) (*session, error) {
+	conn, codec, err := e.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Lock()
+	e.wire = codec
+	e.Unlock()
+
+	return &session{
+		listener: e,
+		conn:     conn,
+		codec:    codec,
+		response: make(chan *responseMessage),
+		done:     make(chan struct{}),
+	}, nil
+}
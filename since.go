@@ -0,0 +1,82 @@
+package eventlistener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const methodGetOffsetByTime = "getOffsetByTime"
+
+// OffsetAt returns the offset of the first event of the event type at or after the time,
+// the head offset if there is none. The server resolves the time if it supports getOffsetByTime,
+// otherwise the history is searched by reading single events, timed by EventListener.Timestamp.
+func (e *EventListener) OffsetAt(ctx context.Context, eventType EventType, at time.Time) (uint64, error) {
+	params := struct {
+		Token     string `json:"token"`
+		Topic     string `json:"topic"`
+		Timestamp string `json:"timestamp"`
+	}{
+		e.Token,
+		eventType.ToString(),
+		at.UTC().Format(time.RFC3339Nano),
+	}
+
+	var offset uint64
+	err := e.Call(ctx, methodGetOffsetByTime, params, &offset)
+
+	rpcError := new(RPCError)
+	if errors.As(err, &rpcError) && rpcError.Code == CodeMethodNotFound {
+		return e.searchOffset(ctx, eventType, at)
+	}
+	return offset, err
+}
+
+// searchOffset finds the first event at or after the time by a binary search over the history.
+func (e *EventListener) searchOffset(ctx context.Context, eventType EventType, at time.Time) (uint64, error) {
+	if e.Timestamp == nil {
+		return 0, errors.New("search by time: EventListener.Timestamp is not set")
+	}
+
+	heads, err := e.HeadOffsets(ctx, eventType)
+	if err != nil {
+		return 0, err
+	}
+
+	low, high := uint64(0), heads[eventType]
+	for low < high {
+		middle := low + (high-low)/2
+
+		events, err := e.ReadRange(ctx, eventType, middle, middle)
+		if err != nil {
+			return 0, err
+		}
+		if len(events) == 0 {
+			return 0, fmt.Errorf("search by time: event %d of %s not found", middle, eventType.ToString())
+		}
+
+		timestamp, ok := e.Timestamp(events[0])
+		if !ok {
+			return 0, fmt.Errorf("search by time: event %d of %s has no timestamp", middle, eventType.ToString())
+		}
+
+		if timestamp.Before(at) {
+			low = middle + 1
+		} else {
+			high = middle
+		}
+	}
+
+	return low, nil
+}
+
+// SubscribeSince subscribes to the event type from the first event at or after the time, see OffsetAt.
+func (e *EventListener) SubscribeSince(eventType EventType, since time.Time) (bool, error) {
+	offset, err := e.OffsetAt(context.Background(), eventType, since)
+	if err != nil {
+		return false, err
+	}
+
+	return e.Subscribe(eventType, offset)
+}
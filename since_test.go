@@ -0,0 +1,78 @@
+package eventlistener
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func publishTimed(server *testServer, eventType EventType, start time.Time, count int) {
+	for i := 0; i < count; i++ {
+		server.publish(eventType, fmt.Sprintf(`{"timestamp":%q}`, start.Add(time.Duration(i)*time.Minute).Format(time.RFC3339)))
+	}
+}
+
+func TestEventListener_OffsetAt(t *testing.T) {
+	start := time.Date(2020, 1, 1, 14, 0, 0, 0, time.UTC)
+
+	for _, timeIndex := range []bool{true, false} {
+		t.Run(fmt.Sprintf("timeIndex=%v", timeIndex), func(t *testing.T) {
+			server := newTestServer(t)
+			server.timeIndex = timeIndex
+			publishTimed(server, 1, start, 10)
+
+			listener := New(server.addr(), WithTimestamp(DataTimestamp("timestamp")), WithRangeIdleWait(100*time.Millisecond))
+			require.NoError(t, listener.ListenAndServe(context.Background()))
+			defer listener.Close()
+
+			for at, expected := range map[time.Time]uint64{
+				start.Add(-time.Hour): 0,
+				start:                 0,
+				start.Add(3*time.Minute + 30*time.Second): 4,
+				start.Add(9 * time.Minute):                9,
+				start.Add(time.Hour):                      10,
+			} {
+				offset, err := listener.OffsetAt(context.Background(), 1, at)
+				require.NoError(t, err)
+				assert.Equal(t, expected, offset, at)
+			}
+
+			offset, err := listener.OffsetAt(context.Background(), 2, start)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(0), offset)
+		})
+	}
+}
+
+func TestEventListener_OffsetAt_noTimestamp(t *testing.T) {
+	server := newTestServer(t)
+	publishTimed(server, 1, time.Now(), 1)
+
+	listener := newTestListener(t, server, nil)
+	defer listener.Close()
+
+	_, err := listener.OffsetAt(context.Background(), 1, time.Now())
+	assert.EqualError(t, err, "search by time: EventListener.Timestamp is not set")
+}
+
+func TestEventListener_SubscribeSince(t *testing.T) {
+	start := time.Date(2020, 1, 1, 14, 0, 0, 0, time.UTC)
+	server := newTestServer(t)
+	publishTimed(server, 1, start, 5)
+
+	events := make(chan *EventMessage, 10)
+	listener := New(server.addr(), WithEvents(events), WithTimestamp(DataTimestamp("timestamp")))
+	require.NoError(t, listener.ListenAndServe(context.Background()))
+	defer listener.Close()
+
+	ok, err := listener.SubscribeSince(1, start.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	event := receiveEvent(t, events)
+	assert.Equal(t, uint64(2), event.Offset)
+	assert.Equal(t, start.Add(2*time.Minute), event.Timestamp)
+}
@@ -0,0 +1,331 @@
+package eventlistener
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	sinkQueueSize    = 64
+	sinkDrainWait    = 5 * time.Second
+	fileSinkMaxSize  = 100 << 20
+	fileSinkMaxFiles = 5
+)
+
+// Sink receives all events of a listener, e.g. to forward them to another system.
+// Messages are shared with other receivers and must not be modified.
+type Sink interface {
+	Write(ctx context.Context, message *EventMessage) error
+	Close() error
+}
+
+type sinkPump struct {
+	sink      Sink
+	messages  chan *EventMessage
+	drainWait time.Duration
+}
+
+// AddSink attaches the sink to the listener. Each sink is written from its own goroutine,
+// so a slow sink doesn't stall reading until its queue is full. Write errors are logged.
+// The sink is closed when the listener is closed, once its queue is drained. Messages still
+// queued after the drain wait are dropped and counted in the log.
+func (e *EventListener) AddSink(sink Sink) {
+	pump := &sinkPump{
+		sink:      sink,
+		messages:  make(chan *EventMessage, sinkQueueSize),
+		drainWait: sinkDrainWait,
+	}
+
+	e.Lock()
+	e.sinks = append(e.sinks, pump)
+	e.Unlock()
+
+	e.sinkGroup.Add(1)
+	go func() {
+		defer e.sinkGroup.Done()
+		pump.run(e.done, e.logger)
+	}()
+}
+
+// run writes the queued messages until done. The logger is resolved on every line,
+// as the pump may run before the listener is configured and started.
+func (p *sinkPump) run(done <-chan struct{}, logger func() Logger) {
+	log := func() Logger {
+		return logger().Named("sinkPump")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer func() {
+		cancel()
+		if err := p.sink.Close(); err != nil {
+			log().Error("sink close", "error", err)
+		}
+		log().Info(msgPumpStopped)
+	}()
+
+	log().Info(msgPumpRunning)
+	go func() {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return
+		}
+
+		// Writes are cancelled once the drain wait is over
+		timer := time.NewTimer(p.drainWait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		// Once closed, drain rather than pick queued messages at random
+		select {
+		case <-done:
+			p.drain(ctx, log())
+			return
+		default:
+		}
+
+		select {
+		case <-done:
+		case message := <-p.messages:
+			p.write(ctx, log(), message)
+		}
+	}
+}
+
+// drain writes the queued messages until the queue is empty or the drain wait is over.
+func (p *sinkPump) drain(ctx context.Context, log Logger) {
+	for {
+		select {
+		case message := <-p.messages:
+			if ctx.Err() != nil {
+				log.Error("sink dropped messages", "count", 1+len(p.messages))
+				return
+			}
+			p.write(ctx, log, message)
+		default:
+			return
+		}
+	}
+}
+
+func (p *sinkPump) write(ctx context.Context, log Logger, message *EventMessage) {
+	if err := p.sink.Write(ctx, message); err != nil {
+		log.Error("sink write", "error", err)
+	}
+}
+
+// dispatchSinks queues the message for every sink.
+func (e *EventListener) dispatchSinks(message *EventMessage) {
+	e.Lock()
+	sinks := e.sinks
+	e.Unlock()
+
+	for _, pump := range sinks {
+		select {
+		case pump.messages <- message:
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// WriterSink writes events to an io.Writer as JSON lines, one event per line.
+type WriterSink struct {
+	sync.Mutex
+	writer  io.Writer
+	encoder *json.Encoder
+}
+
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+// NewStdoutSink creates a sink writing JSON lines to the standard output.
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+func (s *WriterSink) Write(_ context.Context, message *EventMessage) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, event := range message.Events {
+		if err := s.encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *WriterSink) Close() error {
+	if closer, ok := s.writer.(io.Closer); ok && s.writer != os.Stdout {
+		return closer.Close()
+	}
+	return nil
+}
+
+// FileSink writes events to a file as JSON lines and rotates it by size.
+// Rotated files are named path.1 (newest) to path.N (oldest), at most MaxFiles are kept.
+type FileSink struct {
+	sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	file *os.File
+	size int64
+}
+
+// NewFileSink opens or creates the file at path. A maxSize or maxFiles that isn't positive
+// defaults to 100 MiB and 5 files.
+func NewFileSink(path string, maxSize int64, maxFiles int) (*FileSink, error) {
+	if maxSize <= 0 {
+		maxSize = fileSinkMaxSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = fileSinkMaxFiles
+	}
+
+	s := &FileSink{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *FileSink) Write(_ context.Context, message *EventMessage) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, event := range message.Events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+
+		if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+			if err := s.rotate(); err != nil {
+				return err
+			}
+		}
+
+		n, err := s.file.Write(line)
+		s.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	_ = os.Remove(s.rotated(s.maxFiles))
+	for i := s.maxFiles - 1; i > 0; i-- {
+		if err := os.Rename(s.rotated(i), s.rotated(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if s.maxFiles > 0 {
+		if err := os.Rename(s.path, s.rotated(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) rotated(i int) string {
+	return s.path + "." + strconv.Itoa(i)
+}
+
+func (s *FileSink) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.file.Close()
+}
+
+// SinkConfig describes a sink, so forwarding can be set up from configuration.
+type SinkConfig struct {
+	Type string `json:"type" yaml:"type"` // stdout, file or webhook
+
+	Path     string `json:"path" yaml:"path"`           // file
+	MaxSize  int64  `json:"max_size" yaml:"max_size"`   // file, bytes
+	MaxFiles int    `json:"max_files" yaml:"max_files"` // file
+
+	URL        string   `json:"url" yaml:"url"`                 // webhook
+	Secret     string   `json:"secret" yaml:"secret"`           // webhook, HMAC key
+	Retries    int      `json:"retries" yaml:"retries"`         // webhook
+	RetryDelay Duration `json:"retry_delay" yaml:"retry_delay"` // webhook
+}
+
+// NewSink creates a built-in sink from the config.
+func NewSink(config SinkConfig) (Sink, error) {
+	switch config.Type {
+	case "stdout":
+		return NewStdoutSink(), nil
+	case "file":
+		if config.Path == "" {
+			return nil, fmt.Errorf("file sink: empty path")
+		}
+		return NewFileSink(config.Path, config.MaxSize, config.MaxFiles)
+	case "webhook":
+		if config.URL == "" {
+			return nil, fmt.Errorf("webhook sink: empty url")
+		}
+		sink := NewWebhookSink(config.URL, config.Secret)
+		if config.Retries > 0 {
+			sink.Retries = config.Retries
+		}
+		if config.RetryDelay > 0 {
+			sink.RetryDelay = time.Duration(config.RetryDelay)
+		}
+		return sink, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", config.Type)
+	}
+}
@@ -0,0 +1,178 @@
+package eventlistener
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+type recordSink struct {
+	messages chan *EventMessage
+	closed   chan struct{}
+}
+
+func (s *recordSink) Write(_ context.Context, message *EventMessage) error {
+	s.messages <- message
+	return nil
+}
+
+func (s *recordSink) Close() error {
+	close(s.closed)
+	return nil
+}
+
+func TestEventListener_AddSink(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+
+	sink := &recordSink{messages: make(chan *EventMessage, 10), closed: make(chan struct{})}
+	listener.AddSink(sink)
+
+	server.publish(1, `{"a":1}`)
+	_, err := listener.Subscribe(1, offset)
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(0), receiveEvent(t, sink.messages).Offset)
+
+	listener.Close()
+	_, ok := <-sink.closed
+	assert.False(t, ok)
+}
+
+// blockingSink blocks writes until they are cancelled.
+type blockingSink struct{}
+
+func (blockingSink) Write(ctx context.Context, _ *EventMessage) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingSink) Close() error {
+	return nil
+}
+
+func TestSinkPump_drain(t *testing.T) {
+	done := make(chan struct{})
+	close(done)
+
+	// Queued messages are written on close
+	sink := &recordSink{messages: make(chan *EventMessage, 10), closed: make(chan struct{})}
+	pump := &sinkPump{sink: sink, messages: make(chan *EventMessage, 3), drainWait: time.Second}
+	for i := 0; i < 3; i++ {
+		pump.messages <- &EventMessage{Offset: uint64(i)}
+	}
+	pump.run(done, NopLogger)
+	assert.Len(t, sink.messages, 3)
+
+	// Messages left after the drain wait are dropped and counted
+	core, logs := observer.New(zapcore.InfoLevel)
+	pump = &sinkPump{sink: blockingSink{}, messages: make(chan *EventMessage, 3), drainWait: 20 * time.Millisecond}
+	for i := 0; i < 3; i++ {
+		pump.messages <- &EventMessage{Offset: uint64(i)}
+	}
+	logger := NewZapLogger(zap.New(core))
+	pump.run(done, func() Logger { return logger })
+
+	dropped := logs.FilterMessage("sink dropped messages").All()
+	require.Len(t, dropped, 1)
+	assert.Equal(t, int64(2), dropped[0].ContextMap()["count"])
+}
+
+func TestWriterSink_Write(t *testing.T) {
+	buffer := new(closeBuffer)
+	sink := NewWriterSink(buffer)
+
+	message := &EventMessage{Events: []*Event{{Offset: 0}, {Offset: 1, Data: json.RawMessage(`{"a":2}`)}}}
+	require.NoError(t, sink.Write(context.Background(), message))
+	require.NoError(t, sink.Close())
+	assert.True(t, buffer.closed)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 2)
+
+	event := new(Event)
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), event))
+	assert.Equal(t, uint64(1), event.Offset)
+	assert.JSONEq(t, `{"a":2}`, string(event.Data))
+}
+
+func TestFileSink_rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	sink, err := NewFileSink(path, 100, 2)
+	require.NoError(t, err)
+
+	message := &EventMessage{Events: []*Event{{EventType: 1, Data: json.RawMessage(`"0123456789"`)}}}
+	for i := 0; i < 5; i++ {
+		require.NoError(t, sink.Write(context.Background(), message))
+	}
+	require.NoError(t, sink.Close())
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		assert.True(t, len(data) <= 100, name)
+		assert.NotEmpty(t, data, name)
+	}
+	_, err = os.ReadFile(path + ".3")
+	assert.Error(t, err)
+}
+
+func TestFileSink_defaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	sink, err := NewFileSink(path, 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(fileSinkMaxSize), sink.maxSize)
+	assert.Equal(t, fileSinkMaxFiles, sink.maxFiles)
+
+	message := &EventMessage{Events: []*Event{{EventType: 1, Data: json.RawMessage(`"0123456789"`)}}}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, sink.Write(context.Background(), message))
+	}
+	require.NoError(t, sink.Close())
+
+	_, err = os.Stat(path + ".1")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNewSink(t *testing.T) {
+	sink, err := NewSink(SinkConfig{Type: "stdout"})
+	require.NoError(t, err)
+	assert.IsType(t, &WriterSink{}, sink)
+
+	sink, err = NewSink(SinkConfig{Type: "webhook", URL: "http://localhost", Retries: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, sink.(*WebhookSink).Retries)
+
+	sink, err = NewSink(SinkConfig{Type: "file", Path: filepath.Join(t.TempDir(), "events")})
+	require.NoError(t, err)
+	assert.Equal(t, int64(fileSinkMaxSize), sink.(*FileSink).maxSize)
+	require.NoError(t, sink.Close())
+
+	_, err = NewSink(SinkConfig{Type: "file"})
+	assert.Error(t, err)
+
+	_, err = NewSink(SinkConfig{Type: "kafka"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,91 @@
+package eventlistener
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+const (
+	methodListTopics     = "listTopics"
+	methodGetHeadOffsets = "getHeadOffsets"
+)
+
+// ListTopics returns the event types available on the server.
+func (e *EventListener) ListTopics(ctx context.Context) ([]EventType, error) {
+	params := struct {
+		Token string `json:"token"`
+	}{
+		e.Token,
+	}
+
+	var topics []string
+	if err := e.Call(ctx, methodListTopics, params, &topics); err != nil {
+		return nil, err
+	}
+
+	eventTypes := make([]EventType, 0, len(topics))
+	for _, topic := range topics {
+		eventType, err := ParseEventType(topic)
+		if err != nil {
+			return nil, err
+		}
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Slice(eventTypes, func(i, j int) bool { return eventTypes[i] < eventTypes[j] })
+
+	return eventTypes, nil
+}
+
+// HeadOffsets returns the head offset of the event types, the offset the next event will get.
+// Subscribing at the head receives only new events, the head minus the tracked offset is the lag in events.
+// The heads are observed by the lag tracker, see LagTracker.ObserveHead, call it periodically to keep Lags current.
+func (e *EventListener) HeadOffsets(ctx context.Context, eventTypes ...EventType) (map[EventType]uint64, error) {
+	params := struct {
+		Token  string   `json:"token"`
+		Topics []string `json:"topics"`
+	}{
+		e.Token,
+		make([]string, len(eventTypes)),
+	}
+
+	for i, eventType := range eventTypes {
+		params.Topics[i] = eventType.ToString()
+	}
+
+	var heads map[string]uint64
+	if err := e.Call(ctx, methodGetHeadOffsets, params, &heads); err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[EventType]uint64, len(heads))
+	for topic, offset := range heads {
+		eventType, err := ParseEventType(topic)
+		if err != nil {
+			return nil, err
+		}
+		offsets[eventType] = offset
+		e.lag.ObserveHead(eventType, offset)
+	}
+
+	return offsets, nil
+}
+
+// SubscribeFromLatest subscribes to the event type from its head offset, skipping the history.
+// Events which arrive between the query and the subscription are received,
+// an event type the server has no events of yet is subscribed from 0. The skipped history is committed in the lag tracker.
+func (e *EventListener) SubscribeFromLatest(eventType EventType) (bool, error) {
+	heads, err := e.HeadOffsets(context.Background(), eventType)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := e.Subscribe(eventType, heads[eventType])
+	if ok && heads[eventType] > 0 {
+		// The skipped history isn't lag
+		e.lag.Lock()
+		e.lag.commit(eventType, heads[eventType]-1, time.Time{}, time.Time{})
+		e.lag.Unlock()
+	}
+	return ok, err
+}
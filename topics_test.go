@@ -0,0 +1,59 @@
+package eventlistener
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseEventType(t *testing.T) {
+	eventType, err := ParseEventType(EventType(12).ToString())
+	require.NoError(t, err)
+	assert.Equal(t, EventType(12), eventType)
+
+	for _, topic := range []string{"", "event_", "event_x", "topic_1"} {
+		_, err := ParseEventType(topic)
+		assert.Error(t, err, topic)
+	}
+}
+
+func TestEventListener_ListTopics(t *testing.T) {
+	server := newTestServer(t)
+	server.publish(2, `{}`)
+	server.publish(1, `{}`)
+	server.publish(1, `{}`)
+
+	listener := newTestListener(t, server, nil)
+	defer listener.Close()
+
+	eventTypes, err := listener.ListTopics(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []EventType{1, 2}, eventTypes)
+
+	heads, err := listener.HeadOffsets(context.Background(), 1, 2, 3)
+	require.NoError(t, err)
+	assert.Equal(t, map[EventType]uint64{1: 2, 2: 1, 3: 0}, heads)
+}
+
+func TestEventListener_SubscribeFromLatest(t *testing.T) {
+	server := newTestServer(t)
+	server.publish(1, `{"old":1}`)
+	server.publish(1, `{"old":2}`)
+
+	events := make(chan *EventMessage, 10)
+	listener := newTestListener(t, server, events)
+
+	ok, err := listener.SubscribeFromLatest(1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(2), listener.subscriptions[1])
+	lag, ok := listener.Lag(1)
+	require.True(t, ok)
+	assert.Equal(t, Lag{Head: 1, Committed: 1}, lag)
+
+	server.publish(1, `{"new":3}`)
+	event := receiveEvent(t, events)
+	assert.Equal(t, uint64(2), event.Offset)
+	assert.JSONEq(t, `{"new":3}`, string(event.Data))
+}
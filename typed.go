@@ -0,0 +1,80 @@
+package eventlistener
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TypedEvent is an Event with Data decoded into T.
+type TypedEvent[T any] struct {
+	Offset    uint64
+	Sender    string
+	CasinoID  uint64
+	GameID    uint64
+	RequestID uint64
+	EventType EventType
+	Data      T
+	Raw       json.RawMessage // Data as received.
+	Err       error           // Decode error, Data is zero if set.
+
+	ReceivedAt time.Time
+	Timestamp  time.Time
+}
+
+// DecodeEvent decodes the data of the event into T, keeping the event metadata.
+func DecodeEvent[T any](event *Event) TypedEvent[T] {
+	typed := TypedEvent[T]{
+		Offset:    event.Offset,
+		Sender:    event.Sender,
+		CasinoID:  event.CasinoID,
+		GameID:    event.GameID,
+		RequestID: event.RequestID,
+		EventType: event.EventType,
+		Raw:       event.Data,
+
+		ReceivedAt: event.ReceivedAt,
+		Timestamp:  event.Timestamp,
+	}
+
+	if err := json.Unmarshal(event.Data, &typed.Data); err != nil {
+		var zero T
+		typed.Data = zero
+		typed.Err = fmt.Errorf("decode %s offset %d: %w", event.EventType.ToString(), event.Offset, err)
+	}
+	return typed
+}
+
+// SubscribeTyped subscribes a new consumer of the listener to eventType and decodes the data of its events into T.
+// Events failing to decode are delivered with Err set instead of dropped. The channel is closed
+// when the listener is closed, use a Consumer with DecodeEvent to unsubscribe earlier.
+func SubscribeTyped[T any](l *EventListener, eventType EventType, offset uint64) (<-chan TypedEvent[T], error) {
+	messages := make(chan *EventMessage)
+	consumer := l.NewConsumer(messages)
+
+	ok, err := consumer.Subscribe(eventType, offset)
+	if err == nil && !ok {
+		err = fmt.Errorf("subscribe %s rejected", eventType.ToString())
+	}
+	if err != nil {
+		_ = consumer.Close()
+		return nil, err
+	}
+
+	typed := make(chan TypedEvent[T])
+	go func() {
+		defer close(typed)
+
+		for message := range messages {
+			for _, event := range message.Events {
+				select {
+				case typed <- DecodeEvent[T](event):
+				case <-l.done:
+					return
+				}
+			}
+		}
+	}()
+
+	return typed, nil
+}
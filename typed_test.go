@@ -0,0 +1,55 @@
+package eventlistener
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type testData struct {
+	Amount int `json:"amount"`
+}
+
+func TestDecodeEvent(t *testing.T) {
+	event := &Event{Offset: 3, CasinoID: 1, EventType: 2, Data: json.RawMessage(`{"amount":10}`)}
+
+	typed := DecodeEvent[testData](event)
+	require.NoError(t, typed.Err)
+	assert.Equal(t, 10, typed.Data.Amount)
+	assert.Equal(t, uint64(3), typed.Offset)
+	assert.Equal(t, uint64(1), typed.CasinoID)
+	assert.Equal(t, EventType(2), typed.EventType)
+
+	typed = DecodeEvent[testData](&Event{Data: json.RawMessage(`{"amount":"x"}`)})
+	assert.Error(t, typed.Err)
+	assert.Equal(t, testData{}, typed.Data)
+	assert.Equal(t, `{"amount":"x"}`, string(typed.Raw))
+}
+
+func TestSubscribeTyped(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+
+	server.publish(1, `{"amount":5}`)
+	server.publish(1, `[]`)
+
+	events, err := SubscribeTyped[testData](listener, 1, offset)
+	require.NoError(t, err)
+
+	for i, want := range []int{5, 0} {
+		select {
+		case event := <-events:
+			assert.Equal(t, uint64(i), event.Offset)
+			assert.Equal(t, want, event.Data.Amount)
+			assert.Equal(t, i == 1, event.Err != nil)
+		case <-time.After(waitEventsTimeout):
+			t.Fatal("no events")
+		}
+	}
+
+	listener.Close()
+	_, ok := <-events
+	assert.False(t, ok)
+}
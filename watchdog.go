@@ -0,0 +1,122 @@
+package eventlistener
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidWindow = errors.New("watchdog window must be positive")
+
+// Watchdog detects a subscription which stopped receiving events while the connection is alive.
+type Watchdog struct {
+	Window      time.Duration                                 // Time without events after which the subscription is stalled.
+	OnStall     func(eventType EventType, idle time.Duration) // Called on every stall, optional.
+	Resubscribe bool                                          // Subscribe again from the last tracked offset on stall.
+}
+
+type watchdog struct {
+	Watchdog
+	last time.Time // last event or start of the window, guarded by the listener lock
+	stop chan struct{}
+}
+
+// Watch starts a watchdog of the subscription of eventType, replacing the previous one.
+// The window starts on subscribe and restarts on every event and on every stall.
+// Returns ErrInvalidWindow for a window which isn't positive and keeps the previous watchdog.
+func (e *EventListener) Watch(eventType EventType, config Watchdog) error {
+	if config.Window <= 0 {
+		return ErrInvalidWindow
+	}
+
+	w := &watchdog{
+		Watchdog: config,
+		last:     time.Now(),
+		stop:     make(chan struct{}),
+	}
+
+	e.Lock()
+	if previous, ok := e.watchdogs[eventType]; ok {
+		close(previous.stop)
+	}
+	e.watchdogs[eventType] = w
+	e.Unlock()
+
+	go e.watch(eventType, w)
+	return nil
+}
+
+// Unwatch stops the watchdog of eventType.
+func (e *EventListener) Unwatch(eventType EventType) {
+	e.Lock()
+	defer e.Unlock()
+
+	if w, ok := e.watchdogs[eventType]; ok {
+		close(w.stop)
+		delete(e.watchdogs, eventType)
+	}
+}
+
+func (e *EventListener) watch(eventType EventType, w *watchdog) {
+	timer := time.NewTimer(w.Window)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-w.stop:
+			return
+		case <-timer.C:
+		}
+
+		// Resolved on every tick, Watch may be called before the listener is configured
+		log := e.logger().Named("watchdog").With("topic", eventType.ToString())
+		idle, offset, stalled := e.stalled(eventType, w)
+		if !stalled {
+			timer.Reset(w.Window - idle)
+			continue
+		}
+
+		log.Info("subscription stalled", "idle", idle, "offset", offset)
+		if w.OnStall != nil {
+			w.OnStall(eventType, idle)
+		}
+
+		if w.Resubscribe {
+			if ok, err := e.Seek(eventType, offset); err != nil || !ok {
+				log.Error("resubscribe", "ok", ok, "error", err)
+			}
+		}
+
+		timer.Reset(w.Window)
+	}
+}
+
+// stalled reports whether the subscription had no events for the window and returns the tracked offset.
+// A stall, as well as not being subscribed, restarts the window.
+func (e *EventListener) stalled(eventType EventType, w *watchdog) (time.Duration, uint64, bool) {
+	e.Lock()
+	defer e.Unlock()
+
+	now := time.Now()
+	offset, subscribed := e.subscriptions[eventType]
+	if !subscribed {
+		w.last = now
+		return 0, 0, false
+	}
+
+	idle := now.Sub(w.last)
+	if idle < w.Window {
+		return idle, offset, false
+	}
+
+	w.last = now
+	return idle, offset, true
+}
+
+// touchWatchdog restarts the window of the event type, called with the listener lock held.
+func (e *EventListener) touchWatchdog(eventType EventType, now time.Time) {
+	if w, ok := e.watchdogs[eventType]; ok {
+		w.last = now
+	}
+}
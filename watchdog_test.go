@@ -0,0 +1,78 @@
+package eventlistener
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEventListener_Watch(t *testing.T) {
+	server := newTestServer(t)
+	server.publish(1, `{}`)
+
+	events := make(chan *EventMessage, 1)
+	listener := newTestListener(t, server, events)
+
+	stalls := make(chan time.Duration, 1)
+	require.NoError(t, listener.Watch(1, Watchdog{
+		Window:      100 * time.Millisecond,
+		OnStall:     func(_ EventType, idle time.Duration) { stalls <- idle },
+		Resubscribe: true,
+	}))
+
+	ok, err := listener.Subscribe(1, offset)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(0), receiveEvent(t, events).Offset)
+
+	server.Lock()
+	server.muted = true
+	server.Unlock()
+	server.publish(1, `{}`)
+
+	select {
+	case idle := <-stalls:
+		assert.True(t, idle >= 100*time.Millisecond, idle)
+	case <-time.After(waitEventsTimeout):
+		t.Fatal("no stall")
+	}
+
+	// The resubscribe from the tracked offset delivers the missed event
+	assert.Equal(t, uint64(1), receiveEvent(t, events).Offset)
+	assert.Equal(t, 2, server.count(methodSubscribe))
+	assert.Equal(t, 1, server.count(methodUnsubscribe))
+
+	listener.Unwatch(1)
+	listener.Lock()
+	assert.Empty(t, listener.watchdogs)
+	listener.Unlock()
+}
+
+func TestEventListener_Watch_notSubscribed(t *testing.T) {
+	server := newTestServer(t)
+	listener := newTestListener(t, server, nil)
+
+	stalls := make(chan time.Duration, 1)
+	require.NoError(t, listener.Watch(1, Watchdog{
+		Window:  20 * time.Millisecond,
+		OnStall: func(_ EventType, idle time.Duration) { stalls <- idle },
+	}))
+
+	select {
+	case <-stalls:
+		t.Fatal("stall without subscription")
+	case <-time.After(100 * time.Millisecond):
+	}
+	listener.Close()
+}
+
+func TestEventListener_Watch_invalidWindow(t *testing.T) {
+	listener := NewEventListener("", nil)
+
+	for _, window := range []time.Duration{0, -time.Second} {
+		err := listener.Watch(1, Watchdog{Window: window, Resubscribe: true})
+		assert.Equal(t, ErrInvalidWindow, err)
+	}
+	assert.Empty(t, listener.watchdogs)
+}
@@ -0,0 +1,107 @@
+package eventlistener
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	webhookRetries    = 3
+	webhookRetryDelay = time.Second
+	webhookTimeout    = 10 * time.Second
+
+	// SignatureHeader carries the hex HMAC-SHA256 of the webhook body keyed by the sink secret.
+	SignatureHeader = "X-Signature-256"
+)
+
+// WebhookSink posts event messages as JSON to an HTTP endpoint.
+type WebhookSink struct {
+	URL        string
+	Secret     string        // HMAC key, the body is not signed if empty.
+	Retries    int           // Attempts after the first failed one.
+	RetryDelay time.Duration // Delay before the first retry, doubled after each attempt.
+	Client     *http.Client  // http.DefaultClient if nil.
+}
+
+func NewWebhookSink(url string, secret string) *WebhookSink {
+	return &WebhookSink{
+		URL:        url,
+		Secret:     secret,
+		Retries:    webhookRetries,
+		RetryDelay: webhookRetryDelay,
+		Client:     &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Sign returns the signature of the body as sent in SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Write posts the message, retrying on network errors, 429 and 5xx responses.
+func (s *WebhookSink) Write(ctx context.Context, message *EventMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	delay := s.RetryDelay
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(ctx, body)
+		if err == nil || !retry || attempt >= s.Retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (s *WebhookSink) post(ctx context.Context, body []byte) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	if s.Secret != "" {
+		request.Header.Set(SignatureHeader, Sign(s.Secret, body))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500
+	return retry, fmt.Errorf("webhook %s: %s", s.URL, response.Status)
+}
+
+func (s *WebhookSink) Close() error {
+	return nil
+}
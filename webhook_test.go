@@ -0,0 +1,58 @@
+package eventlistener
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookSink_Write(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, Sign("secret", body), r.Header.Get(SignatureHeader))
+		assert.JSONEq(t, `{"offset":1,"events":[{"offset":1,"sender":"","casino_id":0,"game_id":0,"req_id":0,"event_type":1,"data":null}]}`, string(body))
+
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, "secret")
+	sink.RetryDelay = time.Millisecond
+
+	err := sink.Write(context.Background(), &EventMessage{Offset: 1, Events: []*Event{{Offset: 1, EventType: 1}}})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestWebhookSink_WriteClientError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, "")
+	sink.RetryDelay = time.Millisecond
+
+	err := sink.Write(context.Background(), &EventMessage{})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestWebhookSink_WriteDefaultClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	sink := &WebhookSink{URL: server.URL}
+	assert.NoError(t, sink.Write(context.Background(), &EventMessage{}))
+}